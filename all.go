@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// ========================================
+// 全カテゴリ一括処理
+// ========================================
+
+// mergedOutputName 全カテゴリを統合した結果のファイル名（拡張子なし）
+const mergedOutputName = "all"
+
+// categoryFileName カテゴリ名を出力ファイルやチェックポイントのファイル名（拡張子なし）に使える形にする
+// パス区切りなどファイル名に使えない文字は _ に置き換え、統合ファイルと同じ名前（all、all_ …）には _ を足す
+func categoryFileName(category string) string {
+	name := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) || unicode.IsControl(r) {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(category))
+	if strings.Trim(name, ".") == "" {
+		name = strings.Repeat("_", max(len(name), 1))
+	}
+	if strings.EqualFold(strings.TrimRight(name, "_"), mergedOutputName) {
+		name += "_"
+	}
+	return name
+}
+
+// processAllCategories JSONファイルの全カテゴリを処理し、カテゴリ別ファイルと統合ファイルを書き出す
+// 複数カテゴリに属する武将のページは一度だけ取得する
+// レート制限で中断した場合もそれまでに取得できた武将は書き出す
+func processAllCategories(jsonFile string) error {
+	categorizedNames, err := loadCategorizedNames(jsonFile)
+	if err != nil {
+		return fmt.Errorf("キャラクターファイルの読み込みエラー: %v", err)
+	}
+
+	// --sort input で統合ファイルも characters.json の順序になるよう、カテゴリはファイルの順序で処理する
+	file, err := loadCategoryFile(jsonFile)
+	if err != nil {
+		return fmt.Errorf("キャラクターファイルの読み込みエラー: %v", err)
+	}
+	categories := make([]string, 0, len(file.Categories))
+	fileNames := make(map[string]string)
+	for _, entry := range file.Categories {
+		name := strings.ToLower(categoryFileName(entry.Name))
+		if other, ok := fileNames[name]; ok {
+			return fmt.Errorf("カテゴリ '%s' と '%s' の出力ファイル名が同じになります。どちらかのカテゴリ名を変更してください", other, entry.Name)
+		}
+		fileNames[name] = entry.Name
+		categories = append(categories, entry.Name)
+	}
+
+	targets, membership := buildUniqueTargets(categories, categorizedNames)
+	slog.Info("全カテゴリの武将を処理します", "categories", len(categories), "count", len(targets))
+
+	journal, done, err := openCheckpointJournal(checkpointPath(mergedOutputName, jsonFile), options.Resume)
+	if err != nil {
+		return fmt.Errorf("チェックポイントの読み込みエラー: %v", err)
+	}
+	defer journal.Close()
+
+	if len(done) > 0 {
+		slog.Info("チェックポイントから再開します", "done", len(done))
+	}
+
+	results, scrapeErr := scrapeTargets(targets, done, journal)
+	var validationErr error
+	if scrapeErr == nil {
+		validationErr = enforceValidation(targets, results)
+	}
+
+	byName := make(map[string]Character, len(targets))
+	for i, character := range results {
+		if character == nil {
+			continue
+		}
+		character.Categories = membership[targets[i].key()]
+		byName[targets[i].key()] = *character
+	}
+
+	if err := os.MkdirAll(options.OutputDir, 0o755); err != nil {
+		return fmt.Errorf("出力ディレクトリの作成エラー: %v", err)
+	}
+
+	for _, category := range categories {
+		var characters []Character
+		for _, entry := range categorizedNames[category] {
+			if character, ok := byName[newTarget(entry).key()]; ok {
+				characters = append(characters, character)
+			}
+		}
+		if err := writeCharactersFile(filepath.Join(options.OutputDir, categoryFileName(category)+"."+options.Format), characters); err != nil {
+			return err
+		}
+	}
+
+	merged := collectCharacters(results)
+	if err := writeCharactersFile(filepath.Join(options.OutputDir, mergedOutputName+"."+options.Format), merged); err != nil {
+		return err
+	}
+	if err := exportDatabaseIfRequested(mergedOutputName, merged); err != nil {
+		return err
+	}
+
+	if scrapeErr != nil {
+		return rateLimitAbort(scrapeErr)
+	}
+	if validationErr != nil {
+		return validationErr
+	}
+	return checkPartialFailure(results)
+}
+
+// buildUniqueTargets カテゴリ間で重複する武将を1つにまとめた処理対象と、Target.key() ごとの所属カテゴリを返す
+// 同じ武将が複数のカテゴリに登録されている場合は最初の登録項目の別名・タグを使う
+func buildUniqueTargets(categories []string, categorizedNames map[string][]CharacterEntry) ([]Target, map[string][]string) {
+	var targets []Target
+	membership := make(map[string][]string)
+
+	for _, category := range categories {
+		for _, entry := range categorizedNames[category] {
+			target := newTarget(entry)
+			key := target.key()
+			if _, exists := membership[key]; !exists {
+				targets = append(targets, target)
+			}
+			if !slices.Contains(membership[key], category) {
+				membership[key] = append(membership[key], category)
+			}
+		}
+	}
+
+	return targets, membership
+}
+
+func writeCharactersFile(path string, characters []Character) error {
+	if err := sortCharacters(characters); err != nil {
+		return err
+	}
+
+	output, err := formatCharacters(characters, options.Format)
+	if err != nil {
+		return fmt.Errorf("出力変換エラー: %v", err)
+	}
+
+	if err := os.WriteFile(path, withBOM(output, options.Format), 0o644); err != nil {
+		return fmt.Errorf("%s の書き込みエラー: %v", path, err)
+	}
+	slog.Info("結果を書き出しました", "path", path, "count", len(characters))
+	return nil
+}
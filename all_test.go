@@ -0,0 +1,77 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCategoryFileName(t *testing.T) {
+	tests := []struct {
+		category string
+		want     string
+	}{
+		{"奇才", "奇才"},
+		{"魏/呉", "魏_呉"},
+		{`a\b:c`, "a_b_c"},
+		{"..", "__"},
+		{" ", "_"},
+		{"all", "all_"},
+		{"ALL", "ALL_"},
+		{"all_", "all__"},
+		{"all-stars", "all-stars"},
+	}
+	for _, tt := range tests {
+		if got := categoryFileName(tt.category); got != tt.want {
+			t.Errorf("categoryFileName(%q) = %q; want %q", tt.category, got, tt.want)
+		}
+	}
+}
+
+func TestProcessAllCategories(t *testing.T) {
+	useTestFetchConfig(t)
+	saved := options
+	defer func() { options = saved }()
+	dir := t.TempDir()
+	options.OutputDir = filepath.Join(dir, "output")
+	options.Format = "json"
+	options.Sort = inputSortOrder
+
+	newTestWiki(t, map[string]string{
+		"劉備": officerPageHTML("劉備"),
+		"曹操": officerPageHTML("曹操"),
+		"孫権": officerPageHTML("孫権"),
+	})
+	jsonFile := filepath.Join(dir, "characters.json")
+	if err := os.WriteFile(jsonFile, []byte(`{"蜀": ["劉備"], "all": ["曹操"], "魏/呉": ["曹操", "孫権"]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := processAllCategories(jsonFile); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"蜀.json", "all_.json", "魏_呉.json"} {
+		if _, err := os.Stat(filepath.Join(options.OutputDir, name)); err != nil {
+			t.Errorf("カテゴリ別ファイルが書き出されていません: %v", err)
+		}
+	}
+	// 統合ファイルは characters.json のカテゴリの順序を保つ
+	merged, err := os.ReadFile(filepath.Join(options.OutputDir, "all.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	liu, cao, sun := strings.Index(string(merged), "劉備"), strings.Index(string(merged), "曹操"), strings.Index(string(merged), "孫権")
+	if liu < 0 || !(liu < cao && cao < sun) {
+		t.Errorf("統合ファイルの順序が characters.json と異なります:\n%s", merged)
+	}
+
+	// ファイル名が同じになるカテゴリは取得を始める前にエラーにする
+	if err := os.WriteFile(jsonFile, []byte(`{"魏/呉": ["曹操"], "魏_呉": ["孫権"]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := processAllCategories(jsonFile); err == nil {
+		t.Error("出力ファイル名が重なるカテゴリがエラーになりませんでした")
+	}
+}
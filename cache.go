@@ -0,0 +1,92 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ========================================
+// HTMLキャッシュ
+// ========================================
+
+// CacheEntry キャッシュしたページのメタデータ
+type CacheEntry struct {
+	URL          string    `json:"url"`
+	FetchedAt    time.Time `json:"fetched_at"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+}
+
+func (e *CacheEntry) isFresh(maxAge time.Duration) bool {
+	return time.Since(e.FetchedAt) < maxAge
+}
+
+// cacheKey generateURLで生成したURLからキャッシュファイル名を求める
+func cacheKey(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return hex.EncodeToString(sum[:])
+}
+
+func cachePaths(url string) (bodyPath, metaPath string) {
+	base := filepath.Join(config.CacheDir, cacheKey(url))
+	return base + ".html", base + ".json"
+}
+
+// loadCachedPage キャッシュからページ本文とメタデータを読み込む
+// キャッシュが存在しない場合は nil, nil, nil を返す
+func loadCachedPage(url string) (*CacheEntry, []byte, error) {
+	bodyPath, metaPath := cachePaths(url)
+
+	meta, err := os.ReadFile(metaPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("メタデータ読み込みエラー: %v", err)
+	}
+
+	var entry CacheEntry
+	if err := json.Unmarshal(meta, &entry); err != nil {
+		return nil, nil, fmt.Errorf("メタデータ解析エラー (%s): %v", metaPath, err)
+	}
+
+	body, err := os.ReadFile(bodyPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("本文読み込みエラー: %v", err)
+	}
+
+	return &entry, body, nil
+}
+
+// storeCachedPage ページ本文とメタデータをキャッシュに保存する
+// 本文を先に書き込み、メタデータの存在をもって保存完了とみなす
+func storeCachedPage(entry CacheEntry, body []byte) error {
+	if err := os.MkdirAll(config.CacheDir, 0o755); err != nil {
+		return fmt.Errorf("キャッシュディレクトリ作成エラー: %v", err)
+	}
+
+	bodyPath, metaPath := cachePaths(entry.URL)
+	if err := os.WriteFile(bodyPath, body, 0o644); err != nil {
+		return fmt.Errorf("本文書き込みエラー: %v", err)
+	}
+
+	meta, err := json.MarshalIndent(entry, "", "    ")
+	if err != nil {
+		return fmt.Errorf("メタデータ変換エラー: %v", err)
+	}
+	if err := os.WriteFile(metaPath, meta, 0o644); err != nil {
+		return fmt.Errorf("メタデータ書き込みエラー: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,502 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// ========================================
+// カテゴリ管理
+// ========================================
+
+// categoryFile 武将一覧JSONファイル（characters.json）の内容
+// map と違いカテゴリの並び順とインデントを保ったまま書き戻せる
+type categoryFile struct {
+	Categories      []categoryEntry
+	indent          string
+	trailingNewline bool
+}
+
+// categoryEntry カテゴリ名とそのカテゴリに属する武将の登録項目
+type categoryEntry struct {
+	Name  string
+	Names []CharacterEntry
+}
+
+// index ページ名または名前が一致する登録項目の位置を返す（見つからなければ -1）
+// ページ名の一致を優先し、ページ名の違う同名の武将が複数ある場合はどれか決められないためエラーにする
+func (e *categoryEntry) index(name string) (int, error) {
+	if i := slices.IndexFunc(e.Names, func(entry CharacterEntry) bool { return entry.pageName() == name }); i >= 0 {
+		return i, nil
+	}
+
+	found := -1
+	var pages []string
+	for i, entry := range e.Names {
+		if entry.Name == name {
+			found = i
+			pages = append(pages, entry.pageName())
+		}
+	}
+	if len(pages) > 1 {
+		return -1, fmt.Errorf("カテゴリ '%s' に %s が複数登録されています。ページ名（%s）で指定してください", e.Name, name, strings.Join(pages, ", "))
+	}
+	return found, nil
+}
+
+// plainEntries 名前だけの登録項目を作る
+func plainEntries(names []string) []CharacterEntry {
+	entries := make([]CharacterEntry, len(names))
+	for i, name := range names {
+		entries[i] = CharacterEntry{Name: name}
+	}
+	return entries
+}
+
+// loadCategoryFile 武将一覧JSONファイルをカテゴリの順序どおりに読み込む
+func loadCategoryFile(path string) (*categoryFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("ファイル読み込みエラー: %v", err)
+	}
+
+	file := &categoryFile{
+		indent:          detectIndent(data),
+		trailingNewline: bytes.HasSuffix(data, []byte("\n")),
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := decoder.Token(); err != nil || tok != json.Delim('{') {
+		return nil, fmt.Errorf("JSON解析エラー: %s はカテゴリ名をキーとするオブジェクトではありません", path)
+	}
+	for decoder.More() {
+		tok, err := decoder.Token()
+		if err != nil {
+			return nil, fmt.Errorf("JSON解析エラー: %v", err)
+		}
+		entry := categoryEntry{Name: tok.(string)}
+		if err := decoder.Decode(&entry.Names); err != nil {
+			return nil, fmt.Errorf("JSON解析エラー: カテゴリ %s: %v", entry.Name, err)
+		}
+		file.Categories = append(file.Categories, entry)
+	}
+	if _, err := decoder.Token(); err != nil {
+		return nil, fmt.Errorf("JSON解析エラー: %v", err)
+	}
+
+	return file, nil
+}
+
+// detectIndent 最初にインデントされた行の先頭の空白を返す（見つからなければ4スペース）
+func detectIndent(data []byte) string {
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		trimmed := bytes.TrimLeft(line, " \t")
+		if len(trimmed) > 0 && len(trimmed) < len(line) {
+			return string(line[:len(line)-len(trimmed)])
+		}
+	}
+	return "    "
+}
+
+// save 読み込んだときのインデントで書き戻す
+// 書き込み途中で失敗しても元のファイルが壊れないよう、一時ファイルに書いてから置き換える
+func (f *categoryFile) save(path string) error {
+	var buf bytes.Buffer
+	buf.WriteString("{")
+	for i, entry := range f.Categories {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		buf.WriteString("\n" + f.indent + jsonString(entry.Name) + ": [")
+		for j, name := range entry.Names {
+			if j > 0 {
+				buf.WriteString(",")
+			}
+			buf.WriteString("\n" + f.indent + f.indent + jsonString(name))
+		}
+		if len(entry.Names) > 0 {
+			buf.WriteString("\n" + f.indent)
+		}
+		buf.WriteString("]")
+	}
+	if len(f.Categories) > 0 {
+		buf.WriteString("\n")
+	}
+	buf.WriteString("}")
+	if f.trailingNewline {
+		buf.WriteString("\n")
+	}
+
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// jsonString HTML 用のエスケープをせずに1行の JSON にする
+// 登録項目のオブジェクトも改行せずに1行で書き出す
+func jsonString(v any) string {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(v)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+func (f *categoryFile) find(category string) *categoryEntry {
+	for i := range f.Categories {
+		if f.Categories[i].Name == category {
+			return &f.Categories[i]
+		}
+	}
+	return nil
+}
+
+// add 武将をカテゴリの末尾に追加する（カテゴリがなければ作る）
+// ページ名が同じ武将がすでに含まれている場合は追加せずにそのページ名を返す
+func (f *categoryFile) add(category string, characters []CharacterEntry) (skipped []string) {
+	entry := f.find(category)
+	if entry == nil {
+		f.Categories = append(f.Categories, categoryEntry{Name: category, Names: []CharacterEntry{}})
+		entry = &f.Categories[len(f.Categories)-1]
+	}
+
+	for _, character := range characters {
+		if slices.ContainsFunc(entry.Names, func(e CharacterEntry) bool { return e.pageName() == character.pageName() }) {
+			skipped = append(skipped, character.pageName())
+			continue
+		}
+		entry.Names = append(entry.Names, character)
+	}
+	return skipped
+}
+
+// remove 武将をカテゴリから取り除く（names が空ならカテゴリごと削除する）
+func (f *categoryFile) remove(category string, names []string) error {
+	entry := f.find(category)
+	if entry == nil {
+		return fmt.Errorf("カテゴリ '%s' が見つかりません", category)
+	}
+
+	if len(names) == 0 {
+		f.Categories = slices.DeleteFunc(f.Categories, func(e categoryEntry) bool { return e.Name == category })
+		return nil
+	}
+
+	for _, name := range names {
+		index, err := entry.index(name)
+		if err != nil {
+			return err
+		}
+		if index < 0 {
+			return fmt.Errorf("カテゴリ '%s' に %s は含まれていません", category, name)
+		}
+		entry.Names = slices.Delete(entry.Names, index, index+1)
+	}
+	return nil
+}
+
+// rename カテゴリ名を変更する（並び順は変えない）
+func (f *categoryFile) rename(oldName, newName string) error {
+	entry := f.find(oldName)
+	if entry == nil {
+		return fmt.Errorf("カテゴリ '%s' が見つかりません", oldName)
+	}
+	if f.find(newName) != nil {
+		return fmt.Errorf("カテゴリ '%s' はすでに存在します", newName)
+	}
+	entry.Name = newName
+	return nil
+}
+
+// move 武将を別のカテゴリへ移す（ページ名・別名・タグもそのまま移す）
+// 移動先にページ名が同じ武将がすでにいる場合は、移動元から消えないようにエラーにする
+func (f *categoryFile) move(name, from, to string) error {
+	source := f.find(from)
+	if source == nil {
+		return fmt.Errorf("カテゴリ '%s' が見つかりません", from)
+	}
+	index, err := source.index(name)
+	if err != nil {
+		return err
+	}
+	if index < 0 {
+		return fmt.Errorf("カテゴリ '%s' に %s は含まれていません", from, name)
+	}
+	moved := source.Names[index]
+	if target := f.find(to); target != nil && slices.ContainsFunc(target.Names, func(e CharacterEntry) bool { return e.pageName() == moved.pageName() }) {
+		return fmt.Errorf("カテゴリ '%s' にはすでに %s が含まれています。移動元から取り除くだけなら remove を使ってください", to, moved.pageName())
+	}
+	source.Names = slices.Delete(source.Names, index, index+1)
+	f.add(to, []CharacterEntry{moved})
+	return nil
+}
+
+// runCategoriesCommand categories サブコマンドを実行する
+func runCategoriesCommand(args []string) error {
+	fs := flag.NewFlagSet("categories", flag.ExitOnError)
+	path := fs.String("file", config.DefaultJSONFile, "編集する武将一覧JSONファイル")
+	slug := fs.String("slug", "", "add で追加する武将のwikiページ名（同名の武将を区別する場合。武将名は1人だけ指定する）")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), `使用方法: go run main.go categories [--file JSONファイル] [list]
+        go run main.go categories [--file JSONファイル] add [--slug ページ名] <カテゴリ名> <武将名>...
+        go run main.go categories [--file JSONファイル] remove <カテゴリ名> [武将名...]
+        go run main.go categories [--file JSONファイル] rename <カテゴリ名> <新しいカテゴリ名>
+        go run main.go categories [--file JSONファイル] move <武将名> <移動元カテゴリ> <移動先カテゴリ>
+remove で武将名を省略するとカテゴリごと削除します
+remove・move の武将名には、同名の武将を区別するためにページ名も指定できます
+`)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	positional := fs.Args()
+	action, rest := "list", positional
+	if len(rest) > 0 {
+		// 操作名の後ろに書かれたオプションも受け付ける（categories add --slug ...）
+		action = rest[0]
+		fs.Parse(rest[1:])
+		rest = fs.Args()
+	}
+
+	arity := map[string]func(int) bool{
+		"list":   func(n int) bool { return n <= 1 },
+		"add":    func(n int) bool { return n >= 2 },
+		"remove": func(n int) bool { return n >= 1 },
+		"rename": func(n int) bool { return n == 2 },
+		"move":   func(n int) bool { return n == 3 },
+	}
+	valid, ok := arity[action]
+	if !ok && len(positional) == 1 {
+		// 従来の categories <JSONファイル> 形式
+		action, rest, valid = "list", positional, arity["list"]
+	} else if !ok {
+		fs.Usage()
+		return usageErrorf("不明な操作です: %s", action)
+	}
+	if !valid(len(rest)) {
+		fs.Usage()
+		return usageErrorf("%s の引数が正しくありません", action)
+	}
+
+	if action == "list" {
+		if len(rest) == 1 {
+			*path = rest[0]
+		}
+		return listCategories(*path)
+	}
+
+	file, err := loadCategoryFile(*path)
+	if err != nil {
+		return err
+	}
+
+	if *slug != "" && (action != "add" || len(rest) != 2) {
+		return usageErrorf("--slug は add で武将名を1人だけ指定する場合に使えます")
+	}
+
+	switch action {
+	case "add":
+		for _, name := range rest[1:] {
+			if strings.TrimSpace(name) == "" {
+				return usageErrorf("空の武将名は追加できません")
+			}
+		}
+		entries := plainEntries(rest[1:])
+		entries[0].Slug = *slug
+		for _, name := range file.add(rest[0], entries) {
+			slog.Warn("すでにカテゴリに含まれています", "name", name, "category", rest[0])
+		}
+	case "remove":
+		err = file.remove(rest[0], rest[1:])
+	case "rename":
+		if strings.TrimSpace(rest[1]) == "" {
+			return usageErrorf("空のカテゴリ名には変更できません")
+		}
+		err = file.rename(rest[0], rest[1])
+	case "move":
+		err = file.move(rest[0], rest[1], rest[2])
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := file.save(*path); err != nil {
+		return fmt.Errorf("%s の書き込みエラー: %v", *path, err)
+	}
+	slog.Info("武将一覧を更新しました", "path", *path, "action", action)
+	return nil
+}
+
+// listCategories カテゴリ名と人数をファイルの順序で表示する
+func listCategories(path string) error {
+	file, err := loadCategoryFile(path)
+	if err != nil {
+		return err
+	}
+	for _, entry := range file.Categories {
+		fmt.Printf("%s\t%d\n", entry.Name, len(entry.Names))
+	}
+	return nil
+}
+
+// ----------------------------------------
+// lint
+// ----------------------------------------
+
+// lintIssue 武将一覧の問題1件
+type lintIssue struct {
+	Category string
+	Name     string
+	Problem  string
+}
+
+func (i lintIssue) String() string {
+	if i.Name == "" {
+		return fmt.Sprintf("%s: %s", i.Category, i.Problem)
+	}
+	return fmt.Sprintf("%s: %q %s", i.Category, i.Name, i.Problem)
+}
+
+// lintCategoryFile 重複・空白や全角文字の混入・空のカテゴリを検出する
+func lintCategoryFile(file *categoryFile) []lintIssue {
+	var issues []lintIssue
+	for _, entry := range file.Categories {
+		if len(entry.Names) == 0 {
+			issues = append(issues, lintIssue{Category: entry.Name, Problem: "武将が登録されていません"})
+			continue
+		}
+
+		urls := make([]string, len(entry.Names))
+		for i, character := range entry.Names {
+			urls[i] = generateURL(character.pageName())
+			for _, name := range append([]string{character.Name}, character.Aliases...) {
+				if problem := checkNameText(name); problem != "" {
+					issues = append(issues, lintIssue{Category: entry.Name, Name: name, Problem: problem})
+				}
+			}
+		}
+		for _, duplicate := range findDuplicateURLs(urls) {
+			issues = append(issues, lintIssue{Category: entry.Name, Problem: "重複しています: " + duplicate})
+		}
+	}
+	return issues
+}
+
+// checkNameText 武将名の表記の問題を返す（問題がなければ空文字列）
+func checkNameText(name string) string {
+	switch {
+	case strings.TrimSpace(name) == "":
+		return "空の武将名です"
+	case strings.TrimSpace(name) != name:
+		return "前後に空白があります"
+	case strings.ContainsRune(name, '　'):
+		return "全角スペースを含んでいます"
+	case strings.IndexFunc(name, unicode.IsSpace) >= 0:
+		return "空白を含んでいます"
+	case strings.IndexFunc(name, isFullWidthASCII) >= 0:
+		return "全角英数字・記号を含んでいます"
+	case strings.IndexFunc(name, isHalfWidthKana) >= 0:
+		return "半角カナを含んでいます"
+	}
+	return ""
+}
+
+func isFullWidthASCII(r rune) bool {
+	return '！' <= r && r <= '～'
+}
+
+func isHalfWidthKana(r rune) bool {
+	return '｡' <= r && r <= 'ﾟ'
+}
+
+// runLintCommand lint サブコマンドを実行する
+func runLintCommand(args []string) error {
+	fs := flag.NewFlagSet("lint", flag.ExitOnError)
+	verify := fs.Bool("verify", false, "各武将のwikiページが存在するか取得して確認する")
+	registerFetchFlags(fs)
+	registerLogFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "使用方法: go run main.go lint [オプション] [JSONファイル]\n")
+		fs.PrintDefaults()
+	}
+	if err := parseCommandFlags(fs, args); err != nil {
+		return err
+	}
+
+	path := config.DefaultJSONFile
+	if fs.NArg() > 0 {
+		path = fs.Arg(0)
+	}
+	file, err := loadCategoryFile(path)
+	if err != nil {
+		return err
+	}
+
+	issues := lintCategoryFile(file)
+	if *verify {
+		issues = append(issues, verifyCategoryPages(file)...)
+	}
+
+	for _, issue := range issues {
+		fmt.Println(issue)
+	}
+	if len(issues) > 0 {
+		return fmt.Errorf("%s に %d件 の問題があります", path, len(issues))
+	}
+	slog.Info("問題は見つかりませんでした", "path", path)
+	return nil
+}
+
+// verifyCategoryPages 各武将のページを取得し、武将ページとして読み取れるか確認する
+// 複数カテゴリに属する武将は一度だけ確認する
+func verifyCategoryPages(file *categoryFile) []lintIssue {
+	var issues []lintIssue
+	checked := make(map[string]bool)
+	for _, entry := range file.Categories {
+		for _, registered := range entry.Names {
+			name, page := registered.Name, registered.pageName()
+			if checked[page] || strings.TrimSpace(page) == "" {
+				continue
+			}
+			checked[page] = true
+
+			character, err := extractCharacterInfoWithRetry(context.Background(), generateURL(page), nil)
+			switch {
+			case err != nil:
+				issues = append(issues, lintIssue{Category: entry.Name, Name: name, Problem: fmt.Sprintf("ページを取得できません: %v", err)})
+			case character.Name == "":
+				issues = append(issues, lintIssue{Category: entry.Name, Name: name, Problem: "武将のページではありません"})
+			}
+		}
+	}
+	return issues
+}
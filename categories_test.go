@@ -0,0 +1,126 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCategoryFileEditPreservesLayout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "characters.json")
+	original := "{\n  \"奇才\": [\n    \"曹操\",\n    \"劉備\"\n  ],\n  \"女性\": [\n    \"蔡琰\"\n  ]\n}\n"
+	if err := os.WriteFile(path, []byte(original), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := loadCategoryFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := file.save(path); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != original {
+		t.Fatalf("編集なしで書き戻した内容が変わりました:\n%s", data)
+	}
+
+	file.add("奇才", plainEntries([]string{"曹操", "孫権"}))
+	if err := file.rename("女性", "女傑"); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.move("劉備", "奇才", "蜀"); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.remove("奇才", []string{"呂布"}); err == nil {
+		t.Error("含まれていない武将の削除がエラーになりませんでした")
+	}
+	if err := file.save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "{\n  \"奇才\": [\n    \"曹操\",\n    \"孫権\"\n  ],\n  \"女傑\": [\n    \"蔡琰\"\n  ],\n  \"蜀\": [\n    \"劉備\"\n  ]\n}\n"
+	if data, _ := os.ReadFile(path); string(data) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", data, want)
+	}
+}
+
+func TestLintCategoryFile(t *testing.T) {
+	file := &categoryFile{Categories: []categoryEntry{
+		{Name: "奇才", Names: []CharacterEntry{{Name: "曹操"}, {Name: "曹操"}, {Name: "劉備 "}, {Name: "関　羽"}, {Name: "ＡＢ"}, {Name: "ｿｳｿｳ"}}},
+		{Name: "女性", Names: []CharacterEntry{}},
+	}}
+
+	var got []string
+	for _, issue := range lintCategoryFile(file) {
+		got = append(got, issue.String())
+	}
+	joined := strings.Join(got, "\n")
+
+	for _, want := range []string{"前後に空白", "全角スペース", "全角英数字", "半角カナ", "重複しています", "女性: 武将が登録されていません"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("%q が検出されませんでした:\n%s", want, joined)
+		}
+	}
+	if len(got) != 6 {
+		t.Errorf("問題の件数 = %d; want 6\n%s", len(got), joined)
+	}
+}
+
+func TestCategoryFileSameNameEntries(t *testing.T) {
+	file := &categoryFile{Categories: []categoryEntry{
+		{Name: "女性", Names: []CharacterEntry{{Name: "蔡琰"}, {Name: "張氏", Slug: "張氏(張済の妻)"}}},
+	}}
+
+	// ページ名が違えば同名の武将も追加できる
+	if skipped := file.add("女性", []CharacterEntry{{Name: "張氏", Slug: "張氏(司馬懿の妻)"}}); len(skipped) > 0 {
+		t.Fatalf("同名でページ名の違う武将が追加されませんでした: %v", skipped)
+	}
+	if skipped := file.add("女性", []CharacterEntry{{Name: "張氏", Slug: "張氏(張済の妻)"}}); len(skipped) != 1 {
+		t.Errorf("同じページ名の武将が重複して追加されました")
+	}
+
+	if err := file.remove("女性", []string{"張氏"}); err == nil || !strings.Contains(err.Error(), "複数登録") {
+		t.Errorf("同名の武将が複数ある場合にエラーになりませんでした: %v", err)
+	}
+	if err := file.move("張氏(張済の妻)", "女性", "魏"); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.remove("女性", []string{"張氏"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := file.find("女性").Names; len(got) != 1 || got[0].Name != "蔡琰" {
+		t.Errorf("女性 = %+v", got)
+	}
+	if got := file.find("魏").Names; len(got) != 1 || got[0].Slug != "張氏(張済の妻)" {
+		t.Errorf("魏 = %+v", got)
+	}
+}
+
+func TestCategoryFileMoveToCategoryWithSameEntry(t *testing.T) {
+	file := &categoryFile{Categories: []categoryEntry{
+		{Name: "奇才", Names: []CharacterEntry{{Name: "曹操"}, {Name: "劉備"}}},
+		{Name: "魏", Names: []CharacterEntry{{Name: "曹操"}}},
+	}}
+
+	if err := file.move("曹操", "奇才", "魏"); err == nil {
+		t.Fatal("移動先にすでにいる武将の移動がエラーになりませんでした")
+	}
+	// 移動元からは取り除かない
+	if got := file.find("奇才").Names; len(got) != 2 {
+		t.Errorf("奇才 = %+v", got)
+	}
+}
+
+func TestCategoriesRenameRejectsBlankName(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "characters.json")
+	if err := os.WriteFile(path, []byte(`{"女性": ["蔡琰"]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := runCategoriesCommand([]string{"--file", path, "rename", "女性", " "})
+	if exitCode(err) != exitUsage {
+		t.Errorf("err = %v; want usage error", err)
+	}
+}
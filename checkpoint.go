@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ========================================
+// チェックポイントジャーナル
+// ========================================
+
+// CheckpointRecord ジャーナル1行分の記録
+// Name は武将名（characters.json でページ名を指定した武将はページ名）
+type CheckpointRecord struct {
+	Name      string    `json:"name"`
+	Character Character `json:"character"`
+}
+
+// CheckpointJournal 抽出済みの武将を JSON Lines 形式で追記するジャーナル
+type CheckpointJournal struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// checkpointPath JSONファイルと同じディレクトリに置くチェックポイントのパス（name は categoryFileName で変換済みの名前）
+func checkpointPath(name, jsonFile string) string {
+	if options.CheckpointFile != "" {
+		return options.CheckpointFile
+	}
+	return filepath.Join(filepath.Dir(jsonFile), name+".checkpoint.jsonl")
+}
+
+// openCheckpointJournal ジャーナルを開く
+// resume が true の場合は既存の記録を読み込んで追記し、false の場合は空にしてから書き始める
+func openCheckpointJournal(path string, resume bool) (*CheckpointJournal, map[string]Character, error) {
+	done := make(map[string]Character)
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if resume {
+		records, err := readCheckpointRecords(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, record := range records {
+			done[record.Name] = record.Character
+		}
+		if err := trimPartialLine(path); err != nil {
+			return nil, nil, fmt.Errorf("ジャーナルを修復できません: %v", err)
+		}
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+
+	file, err := os.OpenFile(path, flags, 0o644)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ジャーナルを開けません: %v", err)
+	}
+
+	return &CheckpointJournal{file: file}, done, nil
+}
+
+func readCheckpointRecords(path string) ([]CheckpointRecord, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ジャーナルを開けません: %v", err)
+	}
+	defer file.Close()
+
+	var records []CheckpointRecord
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		var record CheckpointRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			// 強制終了で途中まで書かれた行は読み飛ばす
+			slog.Warn("チェックポイントの壊れた行を読み飛ばします", "path", path, "line", lineNo, "error", err)
+			continue
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("ジャーナル読み込みエラー: %v", err)
+	}
+
+	return records, nil
+}
+
+// trimPartialLine 強制終了で途中まで書かれた最後の行を取り除く
+// 残したまま追記すると、次の記録がその行の続きになって読めなくなる
+func trimPartialLine(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 || data[len(data)-1] == '\n' {
+		return nil
+	}
+	return os.Truncate(path, int64(bytes.LastIndexByte(data, '\n')+1))
+}
+
+// Append 抽出した武将を1行追記する
+func (j *CheckpointJournal) Append(name string, character Character) error {
+	if j == nil {
+		return nil
+	}
+
+	line, err := json.Marshal(CheckpointRecord{Name: name, Character: character})
+	if err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	_, err = j.file.Write(append(line, '\n'))
+	return err
+}
+
+func (j *CheckpointJournal) Close() error {
+	if j == nil {
+		return nil
+	}
+	return j.file.Close()
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestCheckpointJournal(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string // 開く前のジャーナルの内容（"-" ならファイルなし）
+		resume   bool
+		append   []string
+		want     []string // 書き込み後に再開したときに取得済みとみなされる武将
+	}{
+		{
+			name:     "新規に書いて再開する",
+			existing: "-",
+			resume:   false,
+			append:   []string{"曹操", "劉備"},
+			want:     []string{"劉備", "曹操"},
+		},
+		{
+			name:     "ファイルがなくても再開できる",
+			existing: "-",
+			resume:   true,
+			append:   []string{"曹操"},
+			want:     []string{"曹操"},
+		},
+		{
+			name:     "再開すると既存の記録に追記する",
+			existing: `{"name":"曹操","character":{"名前":"曹操"}}` + "\n",
+			resume:   true,
+			append:   []string{"劉備"},
+			want:     []string{"劉備", "曹操"},
+		},
+		{
+			name:     "途中まで書かれた最後の行は読み飛ばし、次の記録は壊さない",
+			existing: `{"name":"曹操","character":{"名前":"曹操"}}` + "\n" + `{"name":"劉備","charac`,
+			resume:   true,
+			append:   []string{"孫権"},
+			want:     []string{"孫権", "曹操"},
+		},
+		{
+			name:     "再開しなければ既存の記録を空にする",
+			existing: `{"name":"曹操","character":{"名前":"曹操"}}` + "\n",
+			resume:   false,
+			append:   []string{"劉備"},
+			want:     []string{"劉備"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "test.checkpoint.jsonl")
+			if tt.existing != "-" {
+				if err := os.WriteFile(path, []byte(tt.existing), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			journal, _, err := openCheckpointJournal(path, tt.resume)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, name := range tt.append {
+				if err := journal.Append(name, Character{Name: name}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := journal.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			journal, done, err := openCheckpointJournal(path, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer journal.Close()
+
+			var got []string
+			for name, character := range done {
+				if character.Name != name {
+					t.Errorf("%s の武将情報が復元されていません: %+v", name, character)
+				}
+				got = append(got, name)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
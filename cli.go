@@ -0,0 +1,466 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ========================================
+// コマンドライン
+// ========================================
+
+// 終了コード
+const (
+	exitOK          = 0
+	exitFailure     = 1 // 設定・入出力などのエラー
+	exitUsage       = 2 // コマンドやオプションの指定誤り
+	exitPartial     = 3 // 一部の武将の取得に失敗した（結果は出力済み）
+	exitRateLimited = 4 // レート制限が解除されず処理を中断した
+)
+
+// envPrefix 環境変数でオプションを指定する場合の接頭辞
+// --base-url は SANGOKUSHI_BASE_URL のように大文字とアンダースコアに置き換える
+const envPrefix = "SANGOKUSHI_"
+
+// usageError コマンドやオプションの指定誤り
+type usageError struct {
+	message string
+}
+
+func (e *usageError) Error() string {
+	return e.message
+}
+
+func usageErrorf(format string, args ...any) error {
+	return &usageError{message: fmt.Sprintf(format, args...)}
+}
+
+// PartialFailureError 一部の武将の取得に失敗したことを表す
+type PartialFailureError struct {
+	Failed int
+	Total  int
+}
+
+func (e *PartialFailureError) Error() string {
+	return fmt.Sprintf("%d人中 %d人 の取得に失敗しました", e.Total, e.Failed)
+}
+
+// checkPartialFailure 取得に失敗した武将（nil の要素）があれば PartialFailureError を返す
+func checkPartialFailure(results []*Character) error {
+	failed := 0
+	for _, character := range results {
+		if character == nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return &PartialFailureError{Failed: failed, Total: len(results)}
+	}
+	return nil
+}
+
+// hasResults 取得できた結果を出力してから返すエラーかどうか
+// 一部の武将の取得失敗と --strict の検証エラーが該当する
+func hasResults(err error) bool {
+	var partialErr *PartialFailureError
+	var validationErr *ValidationError
+	return err == nil || errors.As(err, &partialErr) || errors.As(err, &validationErr)
+}
+
+// exitCode エラーの種類に応じた終了コード
+func exitCode(err error) int {
+	var usageErr *usageError
+	var partialErr *PartialFailureError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &usageErr):
+		return exitUsage
+	case errors.As(err, &partialErr):
+		return exitPartial
+	case isRateLimitError(err):
+		return exitRateLimited
+	default:
+		return exitFailure
+	}
+}
+
+// ----------------------------------------
+// サブコマンド
+// ----------------------------------------
+
+// subcommand 第1引数で指定するサブコマンド
+type subcommand struct {
+	Name    string
+	Summary string
+	Run     func(args []string) error
+}
+
+// subcommands 利用できるサブコマンド（使用方法の表示順）
+// help は subcommands 自体を参照するため run で別に扱う
+var subcommands = []subcommand{
+	{"scrape", "カテゴリの武将ページを取得して出力する", runScrapeCommand},
+	{"get", "指定した武将のページを取得して出力する", runGetCommand},
+	{"spider", "カテゴリの武将からリンクをたどって関連する武将も取得する", runSpiderCommand},
+	{"categories", "武将一覧JSONファイルのカテゴリを表示・編集する", runCategoriesCommand},
+	{"lint", "武将一覧JSONファイルの重複や表記の誤りを検出する", runLintCommand},
+	{"discover", "wikiの武将一覧ページから未登録の武将を探す", runDiscoverCommand},
+	{"export", "結果ファイルやデータベースを別の形式で出力する", runExportCommand},
+	{"query", "結果を絞り込み式で検索する", runQueryCommand},
+	{"diff", "2つの結果を比較する", runDiffCommand},
+	{"serve", "結果を HTTP API で提供する", runServeCommand},
+	{"rules", "現在の設定と解析ルールを表示する", runRulesCommand},
+}
+
+func findSubcommand(name string) (subcommand, bool) {
+	for _, command := range subcommands {
+		if command.Name == name {
+			return command, true
+		}
+	}
+	return subcommand{}, false
+}
+
+// run コマンドラインの残りの引数を実行する
+// サブコマンド名でない場合は従来どおり scrape として扱う（go run main.go 奇才）
+func run(args []string) error {
+	if len(args) > 0 {
+		if args[0] == "help" {
+			return runHelpCommand(args[1:])
+		}
+		if command, ok := findSubcommand(args[0]); ok {
+			return command.Run(args[1:])
+		}
+	}
+	return scrape(args, flag.Usage)
+}
+
+// runHelpCommand 全体またはサブコマンドの使用方法を表示する
+func runHelpCommand(args []string) error {
+	if len(args) == 0 {
+		flag.CommandLine.SetOutput(os.Stdout)
+		flag.Usage()
+		return nil
+	}
+
+	command, ok := findSubcommand(args[0])
+	if !ok {
+		return usageErrorf("不明なサブコマンドです: %s", args[0])
+	}
+	return command.Run([]string{"--help"})
+}
+
+func printUsage() {
+	w := flag.CommandLine.Output()
+	fmt.Fprintf(w, "使用方法: go run main.go [オプション] <サブコマンド> [引数]\n")
+	fmt.Fprintf(w, "        go run main.go [オプション] <カテゴリ名> [JSONファイル]\n\nサブコマンド:\n")
+	for _, command := range subcommands {
+		fmt.Fprintf(w, "  %-11s %s\n", command.Name, command.Summary)
+	}
+	fmt.Fprintf(w, "  %-11s %s\n", "help", "使用方法を表示する（help <サブコマンド> で各コマンドのオプション）")
+	fmt.Fprintf(w, "\n例: go run main.go scrape 奇才\n例: go run main.go scrape --offline 奇才 test.json\n例: go run main.go scrape --all [JSONファイル]\n例: go run main.go get 曹操 劉備\n例: go run main.go spider --depth 2 奇才\n例: go run main.go categories add 奇才 徐庶\n例: go run main.go lint --verify\n例: go run main.go discover --add 未分類\n例: go run main.go --rules rules.json rules dump\n例: go run main.go diff old.json new.json\n例: go run main.go serve output/all.json\n例: go run main.go query '武力 >= 90 and 性格 == 猪突' output/all.json\n")
+	fmt.Fprintf(w, "\nオプションは環境変数 %s<オプション名> でも指定できます（例: %s）\n", envPrefix, envName("base-url"))
+	fmt.Fprintf(w, "終了コード: %d 成功, %d エラー, %d 使用方法の誤り, %d 一部の武将の取得に失敗, %d レート制限による中断\n\nオプション:\n",
+		exitOK, exitFailure, exitUsage, exitPartial, exitRateLimited)
+	flag.PrintDefaults()
+}
+
+// ----------------------------------------
+// オプション
+// ----------------------------------------
+
+// registerFetchFlags 武将ページの取得に関するオプション
+func registerFetchFlags(fs *flag.FlagSet) {
+	fs.StringVar(&config.BaseURL, "base-url", config.BaseURL, "武将ページのURLの先頭部分（--site の既定値を上書きする）")
+	fs.IntVar(&config.MaxRetries, "max-retries", config.MaxRetries, "1人あたりの最大取得回数")
+	fs.DurationVar(&config.BaseDelay, "base-delay", config.BaseDelay, "リトライ時の最初の待ち時間（以降は倍々に増える）")
+	fs.DurationVar(&config.MaxDelay, "max-delay", config.MaxDelay, "リトライ時の待ち時間の上限")
+	fs.DurationVar(&config.RequestDelay, "request-delay", config.RequestDelay, "リクエストの最小間隔")
+	fs.IntVar(&config.RateBurst, "rate-burst", config.RateBurst, "間隔を空けずに続けて送れるリクエスト数")
+	fs.DurationVar(&config.HTTPTimeout, "http-timeout", config.HTTPTimeout, "1リクエストのタイムアウト")
+	fs.BoolVar(&options.Offline, "offline", options.Offline, "キャッシュのみからHTMLを読み込む（ネットワークにアクセスしない）")
+	fs.StringVar(&config.CacheDir, "cache-dir", config.CacheDir, "HTMLキャッシュの保存先ディレクトリ")
+	fs.DurationVar(&config.CacheMaxAge, "cache-max-age", config.CacheMaxAge, "キャッシュの有効期間（0で毎回再検証）")
+	fs.IntVar(&config.Workers, "workers", config.Workers, "同時に取得するワーカー数")
+	fs.DurationVar(&config.Cooldown, "cooldown", config.Cooldown, "リトライしてもレート制限が解除されない場合に全体を停止する時間")
+	fs.IntVar(&config.MaxCooldowns, "max-cooldowns", config.MaxCooldowns, "1回の実行で全体を停止する最大回数（超えた場合は処理を中断する）")
+}
+
+// registerScrapeFlags カテゴリ単位の取得に関するオプション
+func registerScrapeFlags(fs *flag.FlagSet) {
+	fs.StringVar(&config.DefaultJSONFile, "characters", config.DefaultJSONFile, "JSONファイルを省略した場合に読み込む武将一覧")
+	fs.StringVar(&options.CheckpointFile, "checkpoint", options.CheckpointFile, "チェックポイントジャーナルのパス（省略時はJSONファイルと同じ場所の <カテゴリ名>.checkpoint.jsonl）")
+	fs.BoolVar(&options.Resume, "resume", options.Resume, "チェックポイントジャーナルに記録済みの武将をスキップして再開する")
+	fs.BoolVar(&options.AllCategories, "all", options.AllCategories, "JSONファイルの全カテゴリをまとめて処理する")
+	fs.StringVar(&options.OutputDir, "out-dir", options.OutputDir, "--all 指定時にカテゴリ別の結果を書き出すディレクトリ")
+}
+
+// registerOutputFlags 結果の出力形式に関するオプション
+func registerOutputFlags(fs *flag.FlagSet) {
+	fs.StringVar(&options.Format, "format", options.Format, "出力形式 ("+strings.Join(outputFormats, "|")+")")
+	fs.BoolVar(&options.BOM, "bom", options.BOM, "CSV/TSV出力の先頭にUTF-8 BOMを付ける（Excel向け）")
+	fs.BoolVar(&options.LegacySchema, "legacy-schema", options.LegacySchema, "戦法・特技・興味を従来のカンマ区切り文字列で出力する")
+	fs.StringVar(&options.Sort, "sort", options.Sort, "出力の並び順（カンマ区切り、先頭に - で降順。例: -統率,没年,読み。"+inputSortOrder+" でJSONファイルの順序のまま）")
+	fs.StringVar(&options.OutputFile, "output", options.OutputFile, "結果を書き出すファイル（省略時は標準出力）")
+}
+
+// registerResultFlags 取得した結果の検証と保存先に関するオプション
+func registerResultFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&options.Strict, "strict", options.Strict, "抽出結果の検証で問題があった場合に失敗する")
+	fs.StringVar(&options.Clipboard, "clipboard", options.Clipboard, "結果のコピー先 ("+strings.Join(clipboardModes, "|")+"|"+clipboardCommandPrefix+"<コマンド>)")
+	fs.StringVar(&options.DatabaseFile, "db", options.DatabaseFile, "結果を書き込むデータベースファイルのパス")
+	fs.StringVar(&options.RunID, "run-id", options.RunID, "データベースに書き込むスナップショットの run ID（省略時は実行日時）")
+}
+
+// registerLogFlags ログ出力に関するオプション
+func registerLogFlags(fs *flag.FlagSet) {
+	fs.StringVar(&options.LogFormat, "log-format", options.LogFormat, "標準エラー出力に書くログの形式 ("+strings.Join(logFormats, "|")+")")
+	fs.BoolVar(&options.Quiet, "quiet", options.Quiet, "警告とエラーのみをログに出力する")
+	fs.BoolVar(&options.Verbose, "verbose", options.Verbose, "デバッグ情報もログに出力する")
+}
+
+// parseFlags サブコマンドより前に指定された共通オプションを解析する
+// 優先順位はコマンドライン > 環境変数 > ルールファイル > サイトプロファイル > 組み込みの既定値
+// 指定の誤りはサブコマンドのオプションと同じく usageError として返す
+func parseFlags() error {
+	registerFetchFlags(flag.CommandLine)
+	registerScrapeFlags(flag.CommandLine)
+	registerOutputFlags(flag.CommandLine)
+	registerResultFlags(flag.CommandLine)
+	registerLogFlags(flag.CommandLine)
+	rulesFile := flag.String("rules", "", "設定と解析ルールを上書きするルールファイル（JSON）のパス")
+	siteName := flag.String("site", defaultSiteName, "対象wikiのサイトプロファイル ("+strings.Join(siteNames(), "|")+")")
+	flag.Usage = printUsage
+	flag.Parse()
+
+	if err := applyEnvOverrides(flag.CommandLine, os.LookupEnv); err != nil {
+		return usageErrorf("%v", err)
+	}
+	explicit := explicitFlags(flag.CommandLine)
+	// 設定の誤りを報告するログも --log-format の形式で出す
+	setupLogger(stderrStatus)
+
+	if err := applySiteProfile(*siteName); err != nil {
+		return usageErrorf("%v", err)
+	}
+
+	if *rulesFile != "" {
+		if err := loadRulesFile(*rulesFile); err != nil {
+			return usageErrorf("%v", err)
+		}
+	}
+
+	if err := reapplyExplicitFlags(flag.CommandLine, explicit); err != nil {
+		return usageErrorf("%v", err)
+	}
+
+	if err := applySettings(); err != nil {
+		return usageErrorf("設定エラー:\n%v", err)
+	}
+	return nil
+}
+
+// parseCommandFlags サブコマンドのオプションを解析して設定に反映する
+// 既定値は共通オプション・環境変数・ルールファイルを反映した後の値になる
+func parseCommandFlags(fs *flag.FlagSet, args []string) error {
+	fs.Parse(args)
+	if err := applySettings(); err != nil {
+		return usageErrorf("設定エラー:\n%v", err)
+	}
+	return nil
+}
+
+// applySettings 設定を検証し、設定に依存するロガー・レート制限・クリップボードを作り直す
+func applySettings() error {
+	if err := validateSettings(); err != nil {
+		return err
+	}
+
+	setupLogger(stderrStatus)
+	rateLimiter = newRateLimiter(config.RequestDelay, config.RateBurst)
+	clipboard, _ = newClipboard(options.Clipboard) // 指定の誤りは validateSettings で検出済み
+	return nil
+}
+
+// envName オプション名に対応する環境変数名
+func envName(flagName string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// applyEnvOverrides コマンドラインで指定されていないオプションを環境変数の値で設定する
+func applyEnvOverrides(fs *flag.FlagSet, lookupEnv func(string) (string, bool)) error {
+	explicit := explicitFlags(fs)
+
+	var errs []error
+	fs.VisitAll(func(f *flag.Flag) {
+		if _, ok := explicit[f.Name]; ok {
+			return
+		}
+		value, ok := lookupEnv(envName(f.Name))
+		if !ok {
+			return
+		}
+		if err := fs.Set(f.Name, value); err != nil {
+			errs = append(errs, fmt.Errorf("環境変数 %s の値が不正です: %v", envName(f.Name), err))
+		}
+	})
+	return errors.Join(errs...)
+}
+
+// explicitFlags コマンドラインまたは環境変数で指定されたオプションとその値
+func explicitFlags(fs *flag.FlagSet) map[string]string {
+	explicit := make(map[string]string)
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = f.Value.String()
+	})
+	return explicit
+}
+
+// reapplyExplicitFlags サイトプロファイルやルールファイルで上書きされた値を明示的な指定に戻す
+func reapplyExplicitFlags(fs *flag.FlagSet, explicit map[string]string) error {
+	for name, value := range explicit {
+		if err := fs.Set(name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ----------------------------------------
+// scrape / get / export
+// ----------------------------------------
+
+func runScrapeCommand(args []string) error {
+	fs := flag.NewFlagSet("scrape", flag.ExitOnError)
+	registerFetchFlags(fs)
+	registerScrapeFlags(fs)
+	registerOutputFlags(fs)
+	registerResultFlags(fs)
+	registerLogFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "使用方法: go run main.go scrape [オプション] <カテゴリ名> [JSONファイル]\n        go run main.go scrape [オプション] --all [JSONファイル]\n")
+		fs.PrintDefaults()
+	}
+	if err := parseCommandFlags(fs, args); err != nil {
+		return err
+	}
+	return scrape(fs.Args(), fs.Usage)
+}
+
+// scrape カテゴリ（--all の場合は全カテゴリ）の武将を取得して出力する
+func scrape(args []string, usage func()) error {
+	if options.AllCategories {
+		jsonFile := config.DefaultJSONFile
+		if len(args) > 0 {
+			jsonFile = args[0]
+		}
+		return processAllCategories(jsonFile)
+	}
+
+	if len(args) < 1 {
+		showAvailableCategories(config.DefaultJSONFile)
+		usage()
+		return usageErrorf("カテゴリ名を指定してください")
+	}
+
+	category, jsonFile := args[0], config.DefaultJSONFile
+	if len(args) > 1 {
+		jsonFile = args[1]
+	}
+
+	characters, err := processCategory(category, jsonFile)
+	if !hasResults(err) {
+		return err
+	}
+	if emitErr := emitCharacters(category, characters); emitErr != nil {
+		return emitErr
+	}
+	return err
+}
+
+func runGetCommand(args []string) error {
+	fs := flag.NewFlagSet("get", flag.ExitOnError)
+	registerFetchFlags(fs)
+	registerOutputFlags(fs)
+	registerResultFlags(fs)
+	registerLogFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "使用方法: go run main.go get [オプション] <武将名>...\n例: go run main.go get --format tsv 曹操 劉備\n")
+		fs.PrintDefaults()
+	}
+	if err := parseCommandFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return usageErrorf("武将名を指定してください")
+	}
+
+	targets := make([]Target, fs.NArg())
+	for i, name := range fs.Args() {
+		targets[i] = newTarget(CharacterEntry{Name: name})
+	}
+
+	results, err := scrapeTargets(targets, nil, nil)
+	if err != nil {
+		return rateLimitAbort(err)
+	}
+	validationErr := enforceValidation(targets, results)
+
+	if err := emitCharacters("get", collectCharacters(results)); err != nil {
+		return err
+	}
+	if validationErr != nil {
+		return validationErr
+	}
+	return checkPartialFailure(results)
+}
+
+func runExportCommand(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	dbPath := fs.String("db", "", "結果ファイルの代わりに読み込むデータベースファイル")
+	runID := fs.String("run-id", "", "--db から読み込むスナップショットの run ID（省略時は最新）")
+	registerOutputFlags(fs)
+	registerLogFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "使用方法: go run main.go export [オプション] <結果ファイル>\n        go run main.go export [オプション] --db <データベースファイル>\n例: go run main.go export --format csv --bom --output all.csv output/all.json\n")
+		fs.PrintDefaults()
+	}
+	if err := parseCommandFlags(fs, args); err != nil {
+		return err
+	}
+
+	var (
+		characters []Character
+		err        error
+	)
+	switch {
+	case *dbPath != "" && fs.NArg() == 0:
+		characters, err = loadFromDatabase(*dbPath, *runID)
+	case *dbPath == "" && fs.NArg() == 1:
+		characters, err = loadCharactersFile(fs.Arg(0))
+	default:
+		fs.Usage()
+		return usageErrorf("結果ファイルまたは --db を指定してください")
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := sortCharacters(characters); err != nil {
+		return err
+	}
+	output, err := formatCharacters(characters, options.Format)
+	if err != nil {
+		return err
+	}
+	return writeOutput(withBOM(output, options.Format))
+}
+
+// rateLimitAbort レート制限による中断エラーに再実行の案内を付ける
+func rateLimitAbort(err error) error {
+	return fmt.Errorf("レート制限に達しました。しばらく時間を置いてから --resume を付けて再実行してください: %w", err)
+}
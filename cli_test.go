@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{nil, exitOK},
+		{errors.New("書き込みエラー"), exitFailure},
+		{usageErrorf("カテゴリ名を指定してください"), exitUsage},
+		{&PartialFailureError{Failed: 1, Total: 3}, exitPartial},
+		{&ValidationError{Invalid: 2}, exitFailure},
+		{rateLimitAbort(&ProcessingError{Err: &HTTPError{StatusCode: 429, Status: "429 Too Many Requests"}}), exitRateLimited},
+		{fmt.Errorf("最大リトライ回数に達しました: %w", &HTTPError{StatusCode: 503, Status: "503 Service Unavailable"}), exitFailure},
+	}
+	for _, tt := range tests {
+		if got := exitCode(tt.err); got != tt.want {
+			t.Errorf("%v: got %d; want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestApplyEnvOverrides(t *testing.T) {
+	saved := config
+	defer func() { config = saved }()
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	registerFetchFlags(fs)
+	if err := fs.Parse([]string{"--workers", "8"}); err != nil {
+		t.Fatal(err)
+	}
+
+	env := map[string]string{
+		"SANGOKUSHI_WORKERS":    "2",
+		"SANGOKUSHI_BASE_URL":   "https://example.com/wiki/",
+		"SANGOKUSHI_BASE_DELAY": "250ms",
+	}
+	lookup := func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+	if err := applyEnvOverrides(fs, lookup); err != nil {
+		t.Fatal(err)
+	}
+
+	// コマンドラインの指定は環境変数より優先する
+	if config.Workers != 8 {
+		t.Errorf("Workers = %d; want 8", config.Workers)
+	}
+	if config.BaseURL != "https://example.com/wiki/" || config.BaseDelay != 250*time.Millisecond {
+		t.Errorf("BaseURL = %q, BaseDelay = %v", config.BaseURL, config.BaseDelay)
+	}
+
+	env = map[string]string{"SANGOKUSHI_MAX_RETRIES": "たくさん"}
+	if err := applyEnvOverrides(fs, lookup); err == nil {
+		t.Error("不正な環境変数の値がエラーになりませんでした")
+	}
+}
+
+func TestSortErrorIsUsageError(t *testing.T) {
+	saved := options
+	defer func() { options = saved }()
+
+	options.Sort = "身長"
+	if err := sortCharacters([]Character{{Name: "曹操"}}); exitCode(err) != exitUsage {
+		t.Errorf("不正な --sort の終了コード = %d; want %d (%v)", exitCode(err), exitUsage, err)
+	}
+}
+
+func TestStrictIsAResultFlag(t *testing.T) {
+	fetch := flag.NewFlagSet("fetch", flag.ContinueOnError)
+	registerFetchFlags(fetch)
+	result := flag.NewFlagSet("result", flag.ContinueOnError)
+	registerResultFlags(result)
+
+	// 取得だけを行う discover などには --strict を出さない
+	if fetch.Lookup("strict") != nil || result.Lookup("strict") == nil {
+		t.Error("--strict は結果のオプションとして登録してください")
+	}
+}
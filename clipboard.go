@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// ========================================
+// クリップボード
+// ========================================
+
+// ClipboardBackend 出力結果をクリップボードへコピーする手段
+type ClipboardBackend interface {
+	Name() string
+	Copy(text string) error
+}
+
+// clipboardModes --clipboard に指定できる値（command:<コマンド> も指定できる）
+var clipboardModes = []string{"auto", "off", "osc52"}
+
+const clipboardCommandPrefix = "command:"
+
+// clipboard 使用するクリップボード（nil ならコピーしない）
+// テストでは偽のバックエンドに差し替える
+var clipboard ClipboardBackend
+
+// 自動検出で参照する環境（テストで差し替える）
+var (
+	clipboardGOOS        = runtime.GOOS
+	clipboardLookPath    = exec.LookPath
+	clipboardGetenv      = os.Getenv
+	clipboardHasTerminal = hasTerminal
+)
+
+// newClipboard --clipboard の指定からクリップボードを作る
+// auto で使える手段が見つからない場合は nil を返す
+func newClipboard(mode string) (ClipboardBackend, error) {
+	switch {
+	case mode == "off":
+		return nil, nil
+	case mode == "auto":
+		return detectClipboard(), nil
+	case mode == "osc52":
+		return newOSC52Clipboard(), nil
+	case strings.HasPrefix(mode, clipboardCommandPrefix):
+		args := strings.Fields(strings.TrimPrefix(mode, clipboardCommandPrefix))
+		if len(args) == 0 {
+			return nil, fmt.Errorf("--clipboard=%s<コマンド> にコマンドを指定してください", clipboardCommandPrefix)
+		}
+		return commandClipboard{args: args}, nil
+	default:
+		return nil, fmt.Errorf("不明なクリップボード指定です: %s (%s または %s<コマンド> を指定してください)", mode, strings.Join(clipboardModes, ", "), clipboardCommandPrefix)
+	}
+}
+
+// detectClipboard 実行環境で使えるクリップボードを探す
+// Wayland、X11 の順にコマンドを探し、見つからなければ端末の OSC 52 を使う
+// OSC 52 は書き込める端末がある場合のみ使う（リダイレクト先のファイルにエスケープシーケンスを書かない）
+func detectClipboard() ClipboardBackend {
+	var candidates [][]string
+	switch clipboardGOOS {
+	case "darwin":
+		candidates = [][]string{{"pbcopy"}}
+	case "windows":
+		candidates = [][]string{{"clip"}}
+	default:
+		if clipboardGetenv("WAYLAND_DISPLAY") != "" {
+			candidates = append(candidates, []string{"wl-copy"})
+		}
+		if clipboardGetenv("DISPLAY") != "" {
+			candidates = append(candidates,
+				[]string{"xclip", "-selection", "clipboard"},
+				[]string{"xsel", "--clipboard", "--input"})
+		}
+	}
+
+	for _, args := range candidates {
+		if _, err := clipboardLookPath(args[0]); err == nil {
+			return commandClipboard{args: args}
+		}
+	}
+
+	if term := clipboardGetenv("TERM"); term != "" && term != "dumb" && clipboardHasTerminal() {
+		return newOSC52Clipboard()
+	}
+	return nil
+}
+
+// commandClipboard 標準入力からクリップボードへ書き込む外部コマンド（pbcopy、wl-copy など）
+type commandClipboard struct {
+	args []string
+}
+
+func (c commandClipboard) Name() string {
+	return strings.Join(c.args, " ")
+}
+
+func (c commandClipboard) Copy(text string) error {
+	cmd := exec.Command(c.args[0], c.args[1:]...)
+	cmd.Stdin = strings.NewReader(text)
+	return cmd.Run()
+}
+
+// osc52Clipboard 端末のエスケープシーケンス（OSC 52）でコピーする
+// SSH 越しでも手元の端末のクリップボードに届く
+type osc52Clipboard struct {
+	open func() (io.WriteCloser, error)
+}
+
+func newOSC52Clipboard() osc52Clipboard {
+	return osc52Clipboard{open: openTerminal}
+}
+
+// openTerminal 制御端末を開く（標準出力は結果の出力に使うため書き込まない）
+// 制御端末がなければ端末につながった標準エラー出力を使い、それもなければエラーを返す
+func openTerminal() (io.WriteCloser, error) {
+	if tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0); err == nil {
+		return tty, nil
+	}
+	if isTerminal(os.Stderr) {
+		return nopWriteCloser{os.Stderr}, nil
+	}
+	return nil, fmt.Errorf("OSC 52 で書き込める端末がありません")
+}
+
+// hasTerminal openTerminal で端末を開けるかどうか
+func hasTerminal() bool {
+	w, err := openTerminal()
+	if err != nil {
+		return false
+	}
+	w.Close()
+	return true
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+func (c osc52Clipboard) Name() string {
+	return "osc52"
+}
+
+func (c osc52Clipboard) Copy(text string) error {
+	w, err := c.open()
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	_, err = fmt.Fprintf(w, "\x1b]52;c;%s\a", base64.StdEncoding.EncodeToString([]byte(text)))
+	return err
+}
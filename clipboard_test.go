@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+type fakeClipboard struct {
+	copied []string
+}
+
+func (f *fakeClipboard) Name() string { return "fake" }
+
+func (f *fakeClipboard) Copy(text string) error {
+	f.copied = append(f.copied, text)
+	return nil
+}
+
+func TestOutputCharactersCopiesToClipboard(t *testing.T) {
+	fake := &fakeClipboard{}
+	saved := clipboard
+	clipboard = fake
+	defer func() { clipboard = saved }()
+
+	if err := outputCharacters([]Character{{Name: "曹操", Reading: "そうそう"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fake.copied) != 1 || !strings.Contains(fake.copied[0], "曹操") {
+		t.Errorf("クリップボードにコピーされた内容 = %q", fake.copied)
+	}
+}
+
+func TestDetectClipboard(t *testing.T) {
+	savedGOOS, savedLookPath, savedGetenv, savedHasTerminal := clipboardGOOS, clipboardLookPath, clipboardGetenv, clipboardHasTerminal
+	defer func() {
+		clipboardGOOS, clipboardLookPath, clipboardGetenv, clipboardHasTerminal = savedGOOS, savedLookPath, savedGetenv, savedHasTerminal
+	}()
+
+	tests := []struct {
+		goos      string
+		env       map[string]string
+		installed []string
+		terminal  bool
+		want      string
+	}{
+		{"darwin", nil, []string{"pbcopy"}, true, "pbcopy"},
+		{"linux", map[string]string{"WAYLAND_DISPLAY": "wayland-0", "DISPLAY": ":0"}, []string{"wl-copy", "xclip"}, true, "wl-copy"},
+		{"linux", map[string]string{"DISPLAY": ":0"}, []string{"wl-copy", "xclip", "xsel"}, true, "xclip -selection clipboard"},
+		{"linux", map[string]string{"DISPLAY": ":0"}, []string{"xsel"}, true, "xsel --clipboard --input"},
+		{"linux", map[string]string{"TERM": "xterm-256color"}, []string{"xclip"}, true, "osc52"},
+		{"linux", map[string]string{"TERM": "xterm-256color"}, nil, false, ""}, // 端末がなければ OSC 52 は使わない
+		{"linux", map[string]string{"TERM": "dumb"}, nil, true, ""},
+	}
+
+	for _, tt := range tests {
+		clipboardGOOS = tt.goos
+		clipboardGetenv = func(key string) string { return tt.env[key] }
+		clipboardHasTerminal = func() bool { return tt.terminal }
+		clipboardLookPath = func(file string) (string, error) {
+			for _, name := range tt.installed {
+				if name == file {
+					return "/usr/bin/" + file, nil
+				}
+			}
+			return "", exec.ErrNotFound
+		}
+
+		got := ""
+		if backend := detectClipboard(); backend != nil {
+			got = backend.Name()
+		}
+		if got != tt.want {
+			t.Errorf("%s %v %v: got %q; want %q", tt.goos, tt.env, tt.installed, got, tt.want)
+		}
+	}
+}
+
+func TestNewClipboard(t *testing.T) {
+	backend, err := newClipboard("off")
+	if err != nil || backend != nil {
+		t.Errorf("off: got %v, %v; want nil", backend, err)
+	}
+
+	backend, err = newClipboard("command:tee /dev/null")
+	if err != nil || backend.Name() != "tee /dev/null" {
+		t.Errorf("command: got %v, %v", backend, err)
+	}
+
+	for _, mode := range []string{"command:", "pbcopy", ""} {
+		if _, err := newClipboard(mode); err == nil {
+			t.Errorf("%q: エラーになりませんでした", mode)
+		}
+	}
+}
+
+type bufferCloser struct {
+	bytes.Buffer
+}
+
+func (*bufferCloser) Close() error { return nil }
+
+func TestOSC52Clipboard(t *testing.T) {
+	var buf bufferCloser
+	backend := osc52Clipboard{open: func() (io.WriteCloser, error) { return &buf, nil }}
+
+	if err := backend.Copy("曹操"); err != nil {
+		t.Fatal(err)
+	}
+	want := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte("曹操")) + "\a"
+	if buf.String() != want {
+		t.Errorf("got %q; want %q", buf.String(), want)
+	}
+
+	failing := osc52Clipboard{open: func() (io.WriteCloser, error) { return nil, errors.New("端末がありません") }}
+	if err := failing.Copy("曹操"); err == nil {
+		t.Error("端末を開けない場合にエラーになりませんでした")
+	}
+}
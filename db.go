@@ -0,0 +1,319 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// ========================================
+// 組み込みデータベース出力
+// ========================================
+
+// データベース内のテーブル（バケット）名
+// 一覧系の項目は武将ごとの子テーブルに正規化して保存する
+var (
+	runsTable       = []byte("runs")
+	charactersTable = []byte("characters")
+	tacticsTable    = []byte("character_tactics")
+	skillsTable     = []byte("character_skills")
+	interestsTable  = []byte("character_interests")
+	categoriesTable = []byte("character_categories")
+	aliasesTable    = []byte("character_aliases")
+	tagsTable       = []byte("character_tags")
+)
+
+// RunRecord 1回分のスクレイピング結果（スナップショット）の情報
+type RunRecord struct {
+	ID             string    `json:"run_id"`
+	CreatedAt      time.Time `json:"created_at"`
+	Source         string    `json:"source"`
+	CharacterCount int       `json:"character_count"`
+}
+
+// CharacterRow characters テーブルの1行（一覧系の項目を除いた武将情報）
+type CharacterRow struct {
+	RunID        string `json:"run_id"`
+	ID           int    `json:"id"`
+	Name         string `json:"名前"`
+	Reading      string `json:"読み"`
+	Azana        string `json:"字"`
+	Leadership   int    `json:"統率"`
+	Force        int    `json:"武力"`
+	Intelligence int    `json:"知力"`
+	Politics     int    `json:"政治"`
+	Charm        int    `json:"魅力"`
+	Talent       string `json:"奇才"`
+	Greed        string `json:"物欲"`
+	Loyalty      int    `json:"義理"`
+	Personality  string `json:"性格"`
+	Strategy     string `json:"戦略傾向"`
+	DeathYear    int    `json:"没年"`
+	DeathMinus13 int    `json:"没年-13"`
+	Fame         string `json:"重視名声"`
+	EntryName    string `json:"登録名,omitempty"`
+	Page         string `json:"ページ,omitempty"`
+}
+
+// ListItemRow 戦法・特技・興味・カテゴリ・別名・タグの子テーブルの1行
+// Category・Detail は戦法・特技の所属カテゴリ（歩兵・任務など）と括弧書きの補足
+type ListItemRow struct {
+	RunID       string `json:"run_id"`
+	CharacterID int    `json:"character_id"`
+	Position    int    `json:"position"`
+	Name        string `json:"name"`
+	Category    string `json:"category,omitempty"`
+	Detail      string `json:"detail,omitempty"`
+}
+
+// lastRunTime 直前に newRunID で使った時刻（同じ実行内で run ID が重ならないようにする）
+var (
+	runIDMu     sync.Mutex
+	lastRunTime time.Time
+)
+
+// newRunID 実行日時（ミリ秒まで）の run ID を作る
+// 同じミリ秒に続けて作った場合は1ミリ秒ずつずらす
+func newRunID() string {
+	runIDMu.Lock()
+	defer runIDMu.Unlock()
+
+	now := time.Now().Truncate(time.Millisecond)
+	if !now.After(lastRunTime) {
+		now = lastRunTime.Add(time.Millisecond)
+	}
+	lastRunTime = now
+	return now.Format("20060102T150405.000")
+}
+
+// exportToDatabase 武将一覧を run ID 付きのスナップショットとしてデータベースに書き込む
+func exportToDatabase(path, runID, source string, characters []Character) error {
+	if runID == "" || strings.Contains(runID, "/") {
+		return fmt.Errorf("不正な run ID です: %q", runID)
+	}
+
+	db, err := bolt.Open(path, 0o644, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return fmt.Errorf("データベースを開けません: %v", err)
+	}
+	defer db.Close()
+
+	return db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{runsTable, charactersTable, tacticsTable, skillsTable, interestsTable, categoriesTable, aliasesTable, tagsTable} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return fmt.Errorf("テーブル %s の作成エラー: %v", name, err)
+			}
+		}
+
+		if tx.Bucket(runsTable).Get([]byte(runID)) != nil {
+			return fmt.Errorf("run ID %s は既に存在します", runID)
+		}
+
+		for i, character := range characters {
+			id := i + 1
+			if err := putRow(tx.Bucket(charactersTable), characterKey(runID, id), newCharacterRow(runID, id, character)); err != nil {
+				return err
+			}
+
+			lists := []struct {
+				table []byte
+				items []CategorizedItem
+			}{
+				{tacticsTable, character.Tactics},
+				{skillsTable, character.Skills},
+				{interestsTable, uncategorizedItems(character.Interest)},
+				{categoriesTable, uncategorizedItems(character.Categories)},
+				{aliasesTable, uncategorizedItems(character.Aliases)},
+				{tagsTable, uncategorizedItems(character.Tags)},
+			}
+			for _, list := range lists {
+				for pos, item := range list.items {
+					row := ListItemRow{RunID: runID, CharacterID: id, Position: pos + 1, Name: item.Name, Category: item.Category, Detail: item.Detail}
+					if err := putRow(tx.Bucket(list.table), listItemKey(runID, id, pos+1), row); err != nil {
+						return err
+					}
+				}
+			}
+		}
+
+		run := RunRecord{ID: runID, CreatedAt: time.Now(), Source: source, CharacterCount: len(characters)}
+		return putRow(tx.Bucket(runsTable), []byte(runID), run)
+	})
+}
+
+func newCharacterRow(runID string, id int, character Character) CharacterRow {
+	return CharacterRow{
+		RunID:        runID,
+		ID:           id,
+		Name:         character.Name,
+		Reading:      character.Reading,
+		Azana:        character.Azana,
+		Leadership:   character.Leadership,
+		Force:        character.Force,
+		Intelligence: character.Intelligence,
+		Politics:     character.Politics,
+		Charm:        character.Charm,
+		Talent:       character.Talent,
+		Greed:        character.Greed,
+		Loyalty:      character.Loyalty,
+		Personality:  character.Personality,
+		Strategy:     character.Strategy,
+		DeathYear:    character.DeathYear,
+		DeathMinus13: character.DeathMinus13,
+		Fame:         character.Fame,
+		EntryName:    character.EntryName,
+		Page:         character.Page,
+	}
+}
+
+// キーは run ID を先頭に置き、同じスナップショットの行が連続するようにする
+func characterKey(runID string, id int) []byte {
+	return []byte(fmt.Sprintf("%s/%06d", runID, id))
+}
+
+func listItemKey(runID string, characterID, position int) []byte {
+	return []byte(fmt.Sprintf("%s/%06d/%03d", runID, characterID, position))
+}
+
+func putRow(bucket *bolt.Bucket, key []byte, row any) error {
+	value, err := json.Marshal(row)
+	if err != nil {
+		return fmt.Errorf("行の変換エラー: %v", err)
+	}
+	return bucket.Put(key, value)
+}
+
+// loadFromDatabase データベースから指定した run ID のスナップショットを読み込む
+// run ID を省略した場合は最も新しいスナップショットを読み込む
+func loadFromDatabase(path, runID string) ([]Character, error) {
+	db, err := bolt.Open(path, 0o644, &bolt.Options{Timeout: 5 * time.Second, ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("データベースを開けません: %v", err)
+	}
+	defer db.Close()
+
+	var characters []Character
+	err = db.View(func(tx *bolt.Tx) error {
+		runs := tx.Bucket(runsTable)
+		if runs == nil {
+			return fmt.Errorf("%s にスナップショットがありません", path)
+		}
+
+		if runID == "" {
+			latest, err := latestRunID(runs)
+			if err != nil {
+				return err
+			}
+			runID = latest
+		} else if runs.Get([]byte(runID)) == nil {
+			return fmt.Errorf("run ID %s が見つかりません", runID)
+		}
+
+		prefix := []byte(runID + "/")
+		byID := make(map[int]*Character)
+		var order []int
+
+		cursor := tx.Bucket(charactersTable).Cursor()
+		for k, v := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = cursor.Next() {
+			var row CharacterRow
+			if err := json.Unmarshal(v, &row); err != nil {
+				return fmt.Errorf("行の解析エラー (%s): %v", k, err)
+			}
+			character := row.toCharacter()
+			byID[row.ID] = &character
+			order = append(order, row.ID)
+		}
+
+		lists := []struct {
+			table []byte
+			apply func(character *Character, row ListItemRow)
+		}{
+			{tacticsTable, func(c *Character, row ListItemRow) { c.Tactics = append(c.Tactics, row.item()) }},
+			{skillsTable, func(c *Character, row ListItemRow) { c.Skills = append(c.Skills, row.item()) }},
+			{interestsTable, func(c *Character, row ListItemRow) { c.Interest = append(c.Interest, row.Name) }},
+			{categoriesTable, func(c *Character, row ListItemRow) { c.Categories = append(c.Categories, row.Name) }},
+			{aliasesTable, func(c *Character, row ListItemRow) { c.Aliases = append(c.Aliases, row.Name) }},
+			{tagsTable, func(c *Character, row ListItemRow) { c.Tags = append(c.Tags, row.Name) }},
+		}
+		for _, list := range lists {
+			// 別名・タグのテーブルは古いデータベースには存在しない
+			bucket := tx.Bucket(list.table)
+			if bucket == nil {
+				continue
+			}
+			cursor := bucket.Cursor()
+			for k, v := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = cursor.Next() {
+				var row ListItemRow
+				if err := json.Unmarshal(v, &row); err != nil {
+					return fmt.Errorf("行の解析エラー (%s): %v", k, err)
+				}
+				if character, ok := byID[row.CharacterID]; ok {
+					list.apply(character, row)
+				}
+			}
+		}
+
+		for _, id := range order {
+			characters = append(characters, *byID[id])
+		}
+		return nil
+	})
+
+	return characters, err
+}
+
+func latestRunID(runs *bolt.Bucket) (string, error) {
+	var latest RunRecord
+	err := runs.ForEach(func(k, v []byte) error {
+		var run RunRecord
+		if err := json.Unmarshal(v, &run); err != nil {
+			return fmt.Errorf("run の解析エラー (%s): %v", k, err)
+		}
+		if latest.ID == "" || run.CreatedAt.After(latest.CreatedAt) {
+			latest = run
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if latest.ID == "" {
+		return "", fmt.Errorf("スナップショットがありません")
+	}
+	return latest.ID, nil
+}
+
+func (r CharacterRow) toCharacter() Character {
+	return Character{
+		Name:         r.Name,
+		Reading:      r.Reading,
+		Azana:        r.Azana,
+		Leadership:   r.Leadership,
+		Force:        r.Force,
+		Intelligence: r.Intelligence,
+		Politics:     r.Politics,
+		Charm:        r.Charm,
+		Talent:       r.Talent,
+		Interest:     []string{},
+		Greed:        r.Greed,
+		Loyalty:      r.Loyalty,
+		Personality:  r.Personality,
+		Strategy:     r.Strategy,
+		DeathYear:    r.DeathYear,
+		DeathMinus13: r.DeathMinus13,
+		Tactics:      []CategorizedItem{},
+		Skills:       []CategorizedItem{},
+		Fame:         r.Fame,
+		EntryName:    r.EntryName,
+		Page:         r.Page,
+	}
+}
+
+func (r ListItemRow) item() CategorizedItem {
+	return CategorizedItem{Name: r.Name, Category: r.Category, Detail: r.Detail}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDatabaseRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "characters.db")
+	first := []Character{
+		{
+			Name: "曹操", Reading: "そうそう", Azana: "孟徳", Leadership: 96, Force: 72, Intelligence: 91, Politics: 94, Charm: 96,
+			Talent: "奸雄", Interest: []string{"詩歌", "兵法"}, Greed: "大", Loyalty: 10, Personality: "冷静", Strategy: "積極",
+			DeathYear: 220, DeathMinus13: 207, Fame: "高名", EntryName: "曹操", Page: "曹操(魏)",
+			Tactics:    []CategorizedItem{{Name: "突撃", Category: "騎兵", Detail: "Lv3"}, {Name: "鼓舞"}},
+			Skills:     []CategorizedItem{{Name: "仁政", Category: "内政"}},
+			Categories: []string{"奇才", "魏"},
+			Aliases:    []string{"魏武帝"},
+			Tags:       []string{"君主"},
+		},
+		{Name: "蔡琰", Interest: []string{}, Tactics: []CategorizedItem{}, Skills: []CategorizedItem{}},
+	}
+	second := []Character{{Name: "劉備", Interest: []string{}, Tactics: []CategorizedItem{}, Skills: []CategorizedItem{}}}
+
+	if err := exportToDatabase(path, "run1", "奇才", first); err != nil {
+		t.Fatal(err)
+	}
+	if err := exportToDatabase(path, "run2", "蜀", second); err != nil {
+		t.Fatal(err)
+	}
+	if err := exportToDatabase(path, "run1", "奇才", first); err == nil {
+		t.Error("同じ run ID への書き込みがエラーになりませんでした")
+	}
+
+	got, err := loadFromDatabase(path, "run1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, first) {
+		t.Errorf("run1:\ngot  %+v\nwant %+v", got, first)
+	}
+
+	// run ID を省略すると最も新しいスナップショットを読み込む
+	got, err = loadFromDatabase(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, second) {
+		t.Errorf("latest:\ngot  %+v\nwant %+v", got, second)
+	}
+
+	if _, err := loadFromDatabase(path, "run3"); err == nil {
+		t.Error("存在しない run ID がエラーになりませんでした")
+	}
+}
+
+func TestNewRunIDIsUniqueWithinSameSecond(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		id := newRunID()
+		if seen[id] {
+			t.Fatalf("run ID %s が重複しました", id)
+		}
+		seen[id] = true
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ========================================
+// 差分比較
+// ========================================
+
+// DiffOp JSON Patch に似た形式の差分1件
+type DiffOp struct {
+	Op    string `json:"op"`
+	Path  string `json:"path"`
+	Old   any    `json:"old,omitempty"`
+	Value any    `json:"value,omitempty"`
+}
+
+// runDiffCommand diff サブコマンドを実行する
+func runDiffCommand(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "差分を JSON Patch 形式で出力する")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "使用方法: go run main.go diff [--json] <旧結果> <新結果>\n結果には出力JSONファイルまたはキャッシュディレクトリを指定できます\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return usageErrorf("比較する2つの結果を指定してください")
+	}
+
+	oldCharacters, err := loadCharactersFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	newCharacters, err := loadCharactersFile(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	ops := diffCharacters(oldCharacters, newCharacters)
+	if *asJSON {
+		output, err := json.MarshalIndent(ops, "", "    ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(output))
+		return nil
+	}
+
+	writeDiffText(os.Stdout, ops)
+	return nil
+}
+
+// characterIdentity 名前と読みで武将を識別するキー
+func characterIdentity(character Character) string {
+	return fmt.Sprintf("%s(%s)", character.Name, character.Reading)
+}
+
+func indexCharacters(characters []Character) map[string]Character {
+	index := make(map[string]Character, len(characters))
+	for _, character := range characters {
+		key := characterIdentity(character)
+		if _, exists := index[key]; exists {
+			slog.Warn("同じ名前と読みの武将が複数あります。後のものを使います", "character", key)
+		}
+		index[key] = character
+	}
+	return index
+}
+
+// diffCharacters 2つの結果を名前と読みで突き合わせ、追加・削除・項目ごとの変更を返す
+func diffCharacters(oldCharacters, newCharacters []Character) []DiffOp {
+	oldIndex := indexCharacters(oldCharacters)
+	newIndex := indexCharacters(newCharacters)
+
+	keys := make(map[string]bool)
+	for key := range oldIndex {
+		keys[key] = true
+	}
+	for key := range newIndex {
+		keys[key] = true
+	}
+	sortedKeys := make([]string, 0, len(keys))
+	for key := range keys {
+		sortedKeys = append(sortedKeys, key)
+	}
+	sort.Strings(sortedKeys)
+
+	var ops []DiffOp
+	for _, key := range sortedKeys {
+		oldCharacter, inOld := oldIndex[key]
+		newCharacter, inNew := newIndex[key]
+		path := "/" + escapeJSONPointer(key)
+
+		switch {
+		case !inOld:
+			ops = append(ops, DiffOp{Op: "add", Path: path, Value: newCharacter})
+		case !inNew:
+			ops = append(ops, DiffOp{Op: "remove", Path: path, Old: oldCharacter})
+		default:
+			ops = append(ops, diffFields(path, oldCharacter, newCharacter)...)
+		}
+	}
+
+	return ops
+}
+
+func diffFields(path string, oldCharacter, newCharacter Character) []DiffOp {
+	var ops []DiffOp
+
+	oldValue := reflect.ValueOf(oldCharacter)
+	newValue := reflect.ValueOf(newCharacter)
+	t := oldValue.Type()
+	for i := 0; i < t.NumField(); i++ {
+		before := oldValue.Field(i).Interface()
+		after := newValue.Field(i).Interface()
+		if reflect.DeepEqual(before, after) || (isEmptyList(before) && isEmptyList(after)) {
+			continue
+		}
+
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		ops = append(ops, DiffOp{Op: "replace", Path: path + "/" + escapeJSONPointer(name), Old: before, Value: after})
+	}
+
+	return ops
+}
+
+// isEmptyList nil と空の一覧を同じものとして扱うための判定
+func isEmptyList(value any) bool {
+	v := reflect.ValueOf(value)
+	return v.Kind() == reflect.Slice && v.Len() == 0
+}
+
+// escapeJSONPointer RFC 6901 に従ってパスの要素をエスケープする
+func escapeJSONPointer(s string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(s)
+}
+
+func unescapeJSONPointer(s string) string {
+	return strings.NewReplacer("~1", "/", "~0", "~").Replace(s)
+}
+
+func writeDiffText(w io.Writer, ops []DiffOp) {
+	if len(ops) == 0 {
+		fmt.Fprintln(w, "差分はありません")
+		return
+	}
+
+	added, removed, changed := 0, 0, 0
+	current := ""
+	for _, op := range ops {
+		segments := strings.Split(strings.TrimPrefix(op.Path, "/"), "/")
+		key := unescapeJSONPointer(segments[0])
+
+		switch op.Op {
+		case "add":
+			added++
+			fmt.Fprintf(w, "+ %s\n", key)
+		case "remove":
+			removed++
+			fmt.Fprintf(w, "- %s\n", key)
+		case "replace":
+			if key != current {
+				changed++
+				fmt.Fprintf(w, "~ %s\n", key)
+			}
+			fmt.Fprintf(w, "    %s: %s → %s\n", unescapeJSONPointer(segments[1]), describeValue(op.Old), describeValue(op.Value))
+		}
+		current = key
+	}
+
+	fmt.Fprintf(w, "\n追加 %d人, 削除 %d人, 変更 %d人\n", added, removed, changed)
+}
+
+// describeValue 差分表示用に値を1行の文字列にする
+func describeValue(value any) string {
+	switch v := value.(type) {
+	case string:
+		if v == "" {
+			return "(なし)"
+		}
+		return v
+	case []string:
+		if len(v) == 0 {
+			return "(なし)"
+		}
+		return strings.Join(v, ", ")
+	case []CategorizedItem:
+		if len(v) == 0 {
+			return "(なし)"
+		}
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = describeCategorizedItem(item)
+		}
+		return strings.Join(items, ", ")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func describeCategorizedItem(item CategorizedItem) string {
+	text := item.Name
+	if item.Category != "" {
+		text = item.Category + ":" + text
+	}
+	if item.Detail != "" {
+		text += "(" + item.Detail + ")"
+	}
+	return text
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffCharacters(t *testing.T) {
+	oldCharacters := []Character{
+		{Name: "曹操", Reading: "そうそう", Force: 71, Interest: []string{}},
+		{Name: "董卓", Reading: "とうたく"},
+	}
+	newCharacters := []Character{
+		{Name: "曹操", Reading: "そうそう", Force: 72},
+		{Name: "李典", Reading: "りてん"},
+	}
+
+	got := diffCharacters(oldCharacters, newCharacters)
+	want := []DiffOp{
+		{Op: "replace", Path: "/曹操(そうそう)/武力", Old: 71, Value: 72},
+		{Op: "add", Path: "/李典(りてん)", Value: newCharacters[1]},
+		{Op: "remove", Path: "/董卓(とうたく)", Old: oldCharacters[1]},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("diffCharacters() = %+v; want %+v", got, want)
+	}
+}
+
+func TestDecodeCharactersAcceptsLegacySchema(t *testing.T) {
+	legacy := `[{"名前": "曹操", "読み": "そうそう", "戦法": "突破, 蹂躙", "興味": ""}]`
+
+	characters, err := decodeCharacters([]byte(legacy))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []CategorizedItem{{Name: "突破"}, {Name: "蹂躙"}}
+	if len(characters) != 1 || !reflect.DeepEqual(characters[0].Tactics, want) {
+		t.Errorf("decodeCharacters() = %+v; want 戦法 %+v", characters, want)
+	}
+}
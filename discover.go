@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// ========================================
+// 武将一覧ページからの武将の発見
+// ========================================
+
+// maxIndexPages 1回の discover で取得する一覧ページ（サブページを含む）の上限
+const maxIndexPages = 100
+
+// extractPageLinks n 以下のリンクのうちwiki内のページを指すもののページ名を文書順に返す
+// 相対リンクは pageURL を基準に解決し、同じページへのリンクは1つにまとめる
+func extractPageLinks(n *html.Node, pageURL string) []string {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil
+	}
+
+	var names []string
+	for _, a := range findAllNodes(n, "a") {
+		for _, attr := range a.Attr {
+			if attr.Key != "href" {
+				continue
+			}
+			ref, err := url.Parse(strings.TrimSpace(attr.Val))
+			if err != nil {
+				break
+			}
+			link := base.ResolveReference(ref)
+			link.Fragment = ""
+			if name, ok := currentSite.PageName(config.BaseURL, link.String()); ok && !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+			break
+		}
+	}
+	return names
+}
+
+// isIndexSubpage 一覧ページのサブページ（武将一覧/魏 のようなページ）かどうか
+func isIndexSubpage(name string, indexPages []string) bool {
+	for _, index := range indexPages {
+		if strings.HasPrefix(name, index+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// discoverOfficerNames 一覧ページとそのサブページを巡回し、表の中でリンクされているページ名を集める
+// 一覧ページ・サブページ自体は武将として扱わない
+func discoverOfficerNames(ctx context.Context, indexPages []string) ([]string, error) {
+	queue := slices.Clone(indexPages)
+	visited := make(map[string]bool)
+	seen := make(map[string]bool)
+	var names []string
+
+	for len(queue) > 0 {
+		page := queue[0]
+		queue = queue[1:]
+		if visited[page] {
+			continue
+		}
+		if len(visited) >= maxIndexPages {
+			slog.Warn("一覧ページの上限に達したため巡回を打ち切ります", "limit", maxIndexPages)
+			break
+		}
+		visited[page] = true
+
+		pageURL := generateURL(page)
+		doc, err := fetchWithRetry(ctx, pageURL, nil, fetchAndParseHTML)
+		if err != nil {
+			return nil, fmt.Errorf("一覧ページ %s の取得エラー: %w", page, err)
+		}
+
+		for _, link := range extractPageLinks(doc, pageURL) {
+			if isIndexSubpage(link, indexPages) && !visited[link] {
+				queue = append(queue, link)
+			}
+		}
+
+		found := 0
+		for _, table := range findAllNodes(doc, "table") {
+			for _, link := range extractPageLinks(table, pageURL) {
+				if seen[link] || slices.Contains(indexPages, link) || isIndexSubpage(link, indexPages) {
+					continue
+				}
+				seen[link] = true
+				names = append(names, link)
+				found++
+			}
+		}
+		slog.Info("一覧ページを読み込みました", "page", page, "found", found)
+	}
+
+	return names, nil
+}
+
+// missingNames characters.json に登録されていない名前を返す
+// 登録項目の名前・ページ名・別名のいずれかに一致すれば登録済みとみなす
+func missingNames(names []string, categorizedNames map[string][]CharacterEntry) []string {
+	known := make(map[string]bool)
+	for _, entries := range categorizedNames {
+		for _, entry := range entries {
+			known[entry.Name] = true
+			known[entry.pageName()] = true
+			for _, alias := range entry.Aliases {
+				known[alias] = true
+			}
+		}
+	}
+
+	var missing []string
+	for _, name := range names {
+		if !known[name] {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// runDiscoverCommand discover サブコマンドを実行する
+func runDiscoverCommand(args []string) error {
+	fs := flag.NewFlagSet("discover", flag.ExitOnError)
+	path := fs.String("file", config.DefaultJSONFile, "照合する武将一覧JSONファイル")
+	index := fs.String("index", strings.Join(currentSite.IndexPages, ","), "巡回する一覧ページ名（カンマ区切り）")
+	category := fs.String("add", "", "見つかった武将を追加するカテゴリ（なければ作る）")
+	verify := fs.Bool("verify", false, "見つかった各ページを取得し、武将ページのものだけを報告する")
+	registerFetchFlags(fs)
+	registerLogFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "使用方法: go run main.go discover [オプション]\n例: go run main.go discover --verify --add 未分類\n")
+		fs.PrintDefaults()
+	}
+	if err := parseCommandFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return usageErrorf("不明な引数です: %s", strings.Join(fs.Args(), " "))
+	}
+
+	var indexPages []string
+	for _, page := range strings.Split(*index, ",") {
+		if page = strings.TrimSpace(page); page != "" {
+			indexPages = append(indexPages, page)
+		}
+	}
+	if len(indexPages) == 0 {
+		return usageErrorf("一覧ページを --index で指定してください")
+	}
+
+	categorizedNames, err := loadCategorizedNames(*path)
+	if err != nil {
+		return err
+	}
+
+	names, err := discoverOfficerNames(context.Background(), indexPages)
+	if err != nil {
+		return err
+	}
+	missing := missingNames(names, categorizedNames)
+	var partialErr error
+	if *verify {
+		candidates := len(missing)
+		var unverified []string
+		missing, unverified, err = verifyOfficerPages(context.Background(), missing)
+		if err != nil {
+			return fmt.Errorf("レート制限に達したため確認を中断しました。しばらく時間を置いてから再実行してください: %w", err)
+		}
+		if len(unverified) > 0 {
+			slog.Warn("取得に失敗したため武将のページか確認できませんでした", "names", strings.Join(unverified, ","))
+			partialErr = &PartialFailureError{Failed: len(unverified), Total: candidates}
+		}
+	}
+
+	for _, name := range missing {
+		fmt.Println(name)
+	}
+	slog.Info("未登録の武将を検出しました", "found", len(names), "missing", len(missing))
+
+	if *category == "" || len(missing) == 0 {
+		return partialErr
+	}
+	file, err := loadCategoryFile(*path)
+	if err != nil {
+		return err
+	}
+	file.add(*category, plainEntries(missing))
+	if err := file.save(*path); err != nil {
+		return fmt.Errorf("%s の書き込みエラー: %v", *path, err)
+	}
+	slog.Info("武将一覧に追加しました", "path", *path, "category", *category, "count", len(missing))
+	return partialErr
+}
+
+// verifyOfficerPages 各ページを取得し、武将ページ（spider と同じく能力値の表があるページ）を officers に、
+// 取得に失敗して確認できなかったページを unverified に分けて返す
+// レート制限エラーの場合はそれ以上リクエストを送らずに中断し、そのエラーを返す
+func verifyOfficerPages(ctx context.Context, names []string) (officers, unverified []string, err error) {
+	for _, name := range names {
+		var doc *html.Node
+		doc, err = fetchWithRetry(ctx, generateURL(name), nil, fetchAndParseHTML)
+		switch {
+		case err != nil && isRateLimitError(err):
+			return officers, unverified, err
+		case err != nil:
+			slog.Warn("ページを取得できないため確認できません", "name", name, "error", err)
+			unverified = append(unverified, name)
+		case !isCharacterPage(doc):
+			slog.Debug("武将のページではありません", "name", name)
+		default:
+			officers = append(officers, name)
+		}
+	}
+	return officers, unverified, nil
+}
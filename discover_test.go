@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// newTestWiki ページ名と本文のHTMLを返すwikiのテストサーバーを起動し、config.BaseURL をそこに向ける
+func newTestWiki(t *testing.T, pages map[string]string) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, err := url.QueryUnescape(strings.TrimPrefix(r.URL.EscapedPath(), "/wiki/"))
+		body, ok := pages[name]
+		if err != nil || !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("<html><body>" + body + "</body></html>"))
+	}))
+	t.Cleanup(server.Close)
+	config.BaseURL = server.URL + "/wiki/"
+}
+
+func TestDiscoverOfficerNames(t *testing.T) {
+	useTestFetchConfig(t)
+	newTestWiki(t, map[string]string{
+		"武将一覧": `<ul><li><a href="/wiki/` + url.QueryEscape("武将一覧/魏") + `">魏</a></li></ul>
+			<table><tr><td><a href="/wiki/` + url.QueryEscape("曹操") + `">曹操</a></td>
+			<td><a href="` + url.QueryEscape("劉備") + `#stats">劉備</a></td>
+			<td><a href="/wiki/?cmd=edit&page=` + url.QueryEscape("武将一覧") + `">編集</a></td></tr></table>`,
+		"武将一覧/魏": `<table><tr><td><a href="/wiki/` + url.QueryEscape("曹操") + `">曹操</a></td>
+			<td><a href="/wiki/` + url.QueryEscape("夏侯惇") + `">夏侯惇</a></td>
+			<td><a href="/wiki/` + url.QueryEscape("鄒氏") + `">鄒氏</a></td>
+			<td><a href="https://example.com/">外部</a></td></tr></table>`,
+	})
+
+	names, err := discoverOfficerNames(context.Background(), []string{"武将一覧"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"曹操", "劉備", "夏侯惇", "鄒氏"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("got %v; want %v", names, want)
+	}
+
+	categorized := map[string][]CharacterEntry{
+		"奇才": {{Name: "曹操"}},
+		"女性": {{Name: "張氏", Slug: "張氏(張済の妻)", Aliases: []string{"鄒氏"}}},
+	}
+	if got, want := missingNames(names, categorized), []string{"劉備", "夏侯惇"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("missingNames = %v; want %v", got, want)
+	}
+}
+
+func TestVerifyOfficerPages(t *testing.T) {
+	useTestFetchConfig(t)
+	newTestWiki(t, map[string]string{
+		"曹操": officerPageHTML("曹操"),
+		"騎兵": `<strong>騎兵</strong><table><tr><th>戦法</th></tr></table>`,
+	})
+
+	officers, unverified, err := verifyOfficerPages(context.Background(), []string{"曹操", "騎兵", "劉備"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"曹操"}; !reflect.DeepEqual(officers, want) {
+		t.Errorf("officers = %v; want %v", officers, want)
+	}
+	// 取得できなかったページは武将でないとはみなさない
+	if want := []string{"劉備"}; !reflect.DeepEqual(unverified, want) {
+		t.Errorf("unverified = %v; want %v", unverified, want)
+	}
+}
+
+func TestVerifyOfficerPagesStopsOnRateLimit(t *testing.T) {
+	useTestFetchConfig(t)
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	t.Cleanup(server.Close)
+	config.BaseURL = server.URL + "/wiki/"
+
+	_, _, err := verifyOfficerPages(context.Background(), []string{"曹操", "劉備"})
+	if !isRateLimitError(err) {
+		t.Fatalf("err = %v; want rate limit error", err)
+	}
+	if got := requests.Load(); got != int32(config.MaxRetries) {
+		t.Errorf("requests = %d; want %d（2人目は取得しない）", got, config.MaxRetries)
+	}
+}
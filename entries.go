@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// ========================================
+// 武将一覧の登録項目
+// ========================================
+
+// CharacterEntry characters.json に登録された武将1人分
+// 名前だけの文字列と、wikiページ名・別名・タグを持つオブジェクトのどちらでも書ける
+//
+//	"曹操"
+//	{"name": "張氏", "slug": "張氏(張済の妻)", "aliases": ["鄒氏"], "tags": ["張繍"]}
+//
+// Slug は名前だけではページが一意に決まらない武将に指定するwikiのページ名
+type CharacterEntry struct {
+	Name    string   `json:"name"`
+	Slug    string   `json:"slug,omitempty"`
+	Aliases []string `json:"aliases,omitempty"`
+	Tags    []string `json:"tags,omitempty"`
+}
+
+// characterEntryObject CharacterEntry のオブジェクト形式（MarshalJSON・UnmarshalJSON の再帰を避ける）
+type characterEntryObject CharacterEntry
+
+func (e *CharacterEntry) UnmarshalJSON(data []byte) error {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '"' {
+		*e = CharacterEntry{}
+		return json.Unmarshal(trimmed, &e.Name)
+	}
+
+	var object characterEntryObject
+	if err := json.Unmarshal(data, &object); err != nil {
+		return fmt.Errorf("武将は名前の文字列か name を持つオブジェクトで指定してください: %v", err)
+	}
+	if object.Name == "" {
+		return fmt.Errorf("name のない武将が登録されています: %s", data)
+	}
+	*e = CharacterEntry(object)
+	return nil
+}
+
+// MarshalJSON 名前しかない場合は従来どおり文字列で書き出す
+func (e CharacterEntry) MarshalJSON() ([]byte, error) {
+	if e.isPlain() {
+		return json.Marshal(e.Name)
+	}
+	return json.Marshal(characterEntryObject(e))
+}
+
+// isPlain 名前以外の情報を持たないかどうか
+func (e CharacterEntry) isPlain() bool {
+	return e.Slug == "" && len(e.Aliases) == 0 && len(e.Tags) == 0
+}
+
+// pageName wikiのページ名（Slug があればそれ、なければ名前）
+func (e CharacterEntry) pageName() string {
+	if e.Slug != "" {
+		return e.Slug
+	}
+	return e.Name
+}
+
+// newTarget 登録項目から処理対象を作る
+func newTarget(entry CharacterEntry) Target {
+	return Target{Name: entry.Name, URL: generateURL(entry.pageName()), Entry: entry}
+}
+
+// key チェックポイントやカテゴリ間の重複判定に使う識別子
+// 同じ名前でもページ名が異なれば別の武将として扱う
+func (t Target) key() string {
+	if t.Entry.Name == "" {
+		return t.Name
+	}
+	return t.Entry.pageName()
+}
+
+// applyEntry 取得した武将に characters.json のどの項目から取得したかを記録する
+func (c *Character) applyEntry(entry CharacterEntry) {
+	c.EntryName = entry.Name
+	c.Page = entry.Slug
+	c.Aliases = entry.Aliases
+	c.Tags = entry.Tags
+}
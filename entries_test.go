@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCharacterEntryJSON(t *testing.T) {
+	var entries []CharacterEntry
+	data := `["曹操", {"name": "張氏", "slug": "張氏(張済の妻)", "aliases": ["鄒氏"], "tags": ["張繍"]}]`
+	if err := json.Unmarshal([]byte(data), &entries); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []CharacterEntry{
+		{Name: "曹操"},
+		{Name: "張氏", Slug: "張氏(張済の妻)", Aliases: []string{"鄒氏"}, Tags: []string{"張繍"}},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Fatalf("got %+v; want %+v", entries, want)
+	}
+	if entries[0].pageName() != "曹操" || entries[1].pageName() != "張氏(張済の妻)" {
+		t.Errorf("pageName = %q, %q", entries[0].pageName(), entries[1].pageName())
+	}
+
+	// 名前だけの項目は文字列のまま書き戻す
+	if got := jsonString(entries); got != `["曹操",{"name":"張氏","slug":"張氏(張済の妻)","aliases":["鄒氏"],"tags":["張繍"]}]` {
+		t.Errorf("got %s", got)
+	}
+
+	for _, invalid := range []string{`[{"slug": "張氏"}]`, `[1]`} {
+		if err := json.Unmarshal([]byte(invalid), &entries); err == nil {
+			t.Errorf("%s がエラーになりませんでした", invalid)
+		}
+	}
+}
+
+func TestLoadCharactersFromJSONWithEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "characters.json")
+	data := `{"女性": ["蔡琰", {"name": "張氏", "slug": "張氏(張済の妻)"}, {"name": "張氏", "slug": "張氏(司馬懿の妻)", "tags": ["晋"]}]}`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	targets, err := loadCharactersFromJSON("女性", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(targets) != 3 {
+		t.Fatalf("len(targets) = %d; want 3", len(targets))
+	}
+	if targets[1].URL != generateURL("張氏(張済の妻)") || targets[1].key() == targets[2].key() {
+		t.Errorf("同名の武将がページ名で区別されていません: %+v", targets)
+	}
+
+	var character Character
+	character.applyEntry(targets[2].Entry)
+	if character.EntryName != "張氏" || character.Page != "張氏(司馬懿の妻)" || !reflect.DeepEqual(character.Tags, []string{"晋"}) {
+		t.Errorf("登録項目が記録されていません: %+v", character)
+	}
+
+	// ページ名の指定がなければ名前が同じ武将は重複とみなす
+	if err := os.WriteFile(path, []byte(`{"女性": ["張氏", {"name": "張氏", "tags": ["魏"]}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadCharactersFromJSON("女性", path); err == nil {
+		t.Error("重複する武将がエラーになりませんでした")
+	}
+}
+
+func TestCategoryFileKeepsEntryObjects(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "characters.json")
+	original := "{\n  \"女性\": [\n    \"蔡琰\",\n    {\"name\":\"張氏\",\"slug\":\"張氏(張済の妻)\"}\n  ]\n}\n"
+	if err := os.WriteFile(path, []byte(original), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := loadCategoryFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := file.move("張氏", "女性", "魏"); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "{\n  \"女性\": [\n    \"蔡琰\"\n  ],\n  \"魏\": [\n    {\"name\":\"張氏\",\"slug\":\"張氏(張済の妻)\"}\n  ]\n}\n"
+	if data, _ := os.ReadFile(path); string(data) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", data, want)
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ========================================
+// 出力形式
+// ========================================
+
+var outputFormats = []string{"json", "jsonl", "csv", "tsv"}
+
+// utf8BOM Excel に UTF-8 として認識させるためのバイト順マーク
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// formatCharacters 武将一覧を指定された形式に変換する
+// CSV/TSV は一覧項目を持てないため、常にバージョン1スキーマの列構成で出力する
+func formatCharacters(characters []Character, format string) ([]byte, error) {
+	switch format {
+	case "json":
+		if characters == nil {
+			// 1人も取得できなかった場合も "武将" は null ではなく空配列にする
+			characters = []Character{}
+		}
+		var document any = CharacterDocument{SchemaVersion: schemaVersion, Characters: characters}
+		if options.LegacySchema {
+			document = toLegacyCharacters(characters)
+		}
+		output, err := json.MarshalIndent(document, "", "    ")
+		if err != nil {
+			return nil, err
+		}
+		return append(output, '\n'), nil
+	case "jsonl":
+		return formatJSONLines(characters)
+	case "csv":
+		return formatDelimited(characters, ',')
+	case "tsv":
+		return formatDelimited(characters, '\t')
+	default:
+		return nil, fmt.Errorf("不明な出力形式です: %s", format)
+	}
+}
+
+// withBOM --bom 指定時に CSV/TSV の先頭へ BOM を付ける
+func withBOM(output []byte, format string) []byte {
+	if !options.BOM || (format != "csv" && format != "tsv") {
+		return output
+	}
+	return append(append([]byte{}, utf8BOM...), output...)
+}
+
+func formatJSONLines(characters []Character) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, character := range characters {
+		var record any = character
+		if options.LegacySchema {
+			record = toLegacyCharacter(character)
+		}
+		line, err := json.Marshal(record)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+func formatDelimited(characters []Character, comma rune) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	writer.Comma = comma
+
+	if err := writer.Write(characterHeaders()); err != nil {
+		return nil, err
+	}
+	for _, character := range characters {
+		if err := writer.Write(characterRecord(toLegacyCharacter(character))); err != nil {
+			return nil, err
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// characterHeaders LegacyCharacter の JSON タグ名をヘッダー行として返す
+func characterHeaders() []string {
+	t := reflect.TypeOf(LegacyCharacter{})
+	headers := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		headers = append(headers, name)
+	}
+	return headers
+}
+
+// characterRecord LegacyCharacter をヘッダーと同じ並びの文字列に変換する
+func characterRecord(character LegacyCharacter) []string {
+	v := reflect.ValueOf(character)
+	record := make([]string, 0, v.NumField())
+	for i := 0; i < v.NumField(); i++ {
+		record = append(record, formatFieldValue(v.Field(i)))
+	}
+	return record
+}
+
+func formatFieldValue(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Int:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.String:
+		return v.String()
+	case reflect.Slice:
+		items := make([]string, v.Len())
+		for i := range items {
+			items[i] = formatFieldValue(v.Index(i))
+		}
+		return strings.Join(items, ", ")
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
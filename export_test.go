@@ -0,0 +1,16 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatCharactersEmptyIsArray(t *testing.T) {
+	output, err := formatCharacters(nil, "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(output), `"武将": []`) {
+		t.Errorf("武将が空配列になっていません:\n%s", output)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+)
+
+// ========================================
+// 武将情報の項目参照
+// ========================================
+
+// characterField Character の1項目
+// JSON タグ名（統率）と Go のフィールド名（Leadership）のどちらでも参照できる
+type characterField struct {
+	Name   string
+	GoName string
+	Index  int
+	Kind   reflect.Kind
+}
+
+var characterFields = buildCharacterFields()
+
+func buildCharacterFields() []characterField {
+	t := reflect.TypeOf(Character{})
+	fields := make([]characterField, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		fields = append(fields, characterField{
+			Name:   name,
+			GoName: t.Field(i).Name,
+			Index:  i,
+			Kind:   t.Field(i).Type.Kind(),
+		})
+	}
+	return fields
+}
+
+// lookupCharacterField 項目名から項目を探す（Go のフィールド名は大文字小文字を区別しない）
+func lookupCharacterField(name string) (characterField, bool) {
+	for _, field := range characterFields {
+		if field.Name == name || strings.EqualFold(field.GoName, name) {
+			return field, true
+		}
+	}
+	return characterField{}, false
+}
+
+func (f characterField) value(character Character) reflect.Value {
+	return reflect.ValueOf(character).Field(f.Index)
+}
+
+// isList 戦法・特技・興味・カテゴリのような一覧項目かどうか
+func (f characterField) isList() bool {
+	return f.Kind == reflect.Slice
+}
+
+// listContains 一覧項目が指定した値を含むかどうか
+// 戦法・特技は名前またはカテゴリ（騎兵・任務など）が一致すれば含むとみなす
+func listContains(list reflect.Value, text string) bool {
+	for i := 0; i < list.Len(); i++ {
+		switch item := list.Index(i).Interface().(type) {
+		case string:
+			if item == text {
+				return true
+			}
+		case CategorizedItem:
+			if item.Name == text || item.Category == text {
+				return true
+			}
+		}
+	}
+	return false
+}
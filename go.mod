@@ -2,4 +2,9 @@ module sangokushi-extractor
 
 go 1.21
 
-require golang.org/x/net v0.19.0
+require (
+	go.etcd.io/bbolt v1.3.10
+	golang.org/x/net v0.19.0
+)
+
+require golang.org/x/sys v0.15.0 // indirect
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// ========================================
+// 出力結果の読み込み
+// ========================================
+
+// loadCharactersFile 以前の出力結果を読み込む
+// JSON（現行スキーマ・--legacy-schema）と JSON Lines に対応し、
+// ディレクトリを指定した場合はHTMLキャッシュのスナップショットとして解析する
+func loadCharactersFile(path string) ([]Character, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("ファイル読み込みエラー: %v", err)
+	}
+	if info.IsDir() {
+		return loadCacheSnapshot(path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("ファイル読み込みエラー: %v", err)
+	}
+
+	characters, err := decodeCharacters(bytes.TrimPrefix(data, utf8BOM))
+	if err != nil {
+		return nil, fmt.Errorf("%s の解析エラー: %v", path, err)
+	}
+	return characters, nil
+}
+
+func decodeCharacters(data []byte) ([]Character, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil, nil
+	}
+
+	var records []json.RawMessage
+	switch {
+	case trimmed[0] == '[':
+		if err := json.Unmarshal(trimmed, &records); err != nil {
+			return nil, err
+		}
+	case isCharacterDocument(trimmed):
+		var document struct {
+			SchemaVersion int               `json:"スキーマバージョン"`
+			Characters    []json.RawMessage `json:"武将"`
+		}
+		if err := json.Unmarshal(trimmed, &document); err != nil {
+			return nil, err
+		}
+		if document.SchemaVersion > schemaVersion {
+			return nil, fmt.Errorf("未対応のスキーマバージョンです: %d", document.SchemaVersion)
+		}
+		records = document.Characters
+	default:
+		// JSON Lines
+		scanner := bufio.NewScanner(bytes.NewReader(trimmed))
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			if line := bytes.TrimSpace(scanner.Bytes()); len(line) > 0 {
+				records = append(records, json.RawMessage(bytes.Clone(line)))
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	characters := make([]Character, 0, len(records))
+	for i, record := range records {
+		character, err := decodeCharacterRecord(record)
+		if err != nil {
+			return nil, fmt.Errorf("%d件目: %v", i+1, err)
+		}
+		characters = append(characters, character)
+	}
+	return characters, nil
+}
+
+func isCharacterDocument(data []byte) bool {
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return false
+	}
+	_, ok := probe["スキーマバージョン"]
+	return ok
+}
+
+// decodeCharacterRecord 武将1人分を読み込む
+// 一覧項目が文字列の場合はバージョン1スキーマとして変換する
+func decodeCharacterRecord(record json.RawMessage) (Character, error) {
+	var character Character
+	err := json.Unmarshal(record, &character)
+	if err == nil {
+		return character, nil
+	}
+
+	var legacy LegacyCharacter
+	if legacyErr := json.Unmarshal(record, &legacy); legacyErr != nil {
+		return Character{}, err
+	}
+	return fromLegacyCharacter(legacy), nil
+}
+
+// loadCacheSnapshot HTMLキャッシュのディレクトリ内の全ページを解析する
+func loadCacheSnapshot(dir string) ([]Character, error) {
+	metaPaths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var characters []Character
+	for _, metaPath := range metaPaths {
+		body, err := os.ReadFile(strings.TrimSuffix(metaPath, ".json") + ".html")
+		if err != nil {
+			return nil, fmt.Errorf("キャッシュ本文の読み込みエラー: %v", err)
+		}
+
+		doc, err := html.Parse(bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("HTMLパースエラー (%s): %v", metaPath, err)
+		}
+
+		character := currentSite.Extractor.Extract(doc)
+		if character.Name == "" {
+			// 武将ページではない（曖昧さ回避ページなど）
+			continue
+		}
+		characters = append(characters, character)
+	}
+
+	return characters, nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+)
+
+// ========================================
+// ログ出力
+// ========================================
+//
+// 進捗や警告はすべて標準エラー出力にログとして書き出し、
+// 標準出力（または --output のファイル）は抽出結果のデータだけに使う。
+
+// logFormats --log-format に指定できる形式
+var logFormats = []string{"text", "json"}
+
+// setupLogger 設定に従って既定のロガーを差し替える
+// --quiet は警告以上のみ、--verbose はデバッグ情報も出力する
+func setupLogger(w io.Writer) {
+	level := slog.LevelInfo
+	switch {
+	case options.Quiet:
+		level = slog.LevelWarn
+	case options.Verbose:
+		level = slog.LevelDebug
+	}
+
+	handlerOptions := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	if options.LogFormat == "json" {
+		handler = slog.NewJSONHandler(w, handlerOptions)
+	} else {
+		handler = slog.NewTextHandler(w, handlerOptions)
+	}
+	slog.SetDefault(slog.New(handler))
+}
+
+// writeOutput 抽出結果を --output のファイル、指定がなければ標準出力に書き出す
+func writeOutput(data []byte) error {
+	if options.OutputFile == "" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+
+	if err := os.WriteFile(options.OutputFile, data, 0o644); err != nil {
+		return fmt.Errorf("%s の書き込みエラー: %v", options.OutputFile, err)
+	}
+	slog.Info("結果を書き出しました", "path", options.OutputFile)
+	return nil
+}
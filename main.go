@@ -1,19 +1,19 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
-	"net/url"
 	"os"
-	"os/exec"
-	"runtime"
 	"slices"
-	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"golang.org/x/net/html"
@@ -23,14 +23,43 @@ import (
 // 設定と定数
 // ========================================
 
-// Config アプリケーション設定
+// Config 取得処理の設定（ルールファイルの config で上書きできる）
 type Config struct {
 	DefaultJSONFile string
 	BaseURL         string
 	MaxRetries      int
 	BaseDelay       time.Duration
+	MaxDelay        time.Duration
+	Cooldown        time.Duration
+	MaxCooldowns    int
 	RequestDelay    time.Duration
 	HTTPTimeout     time.Duration
+	CacheDir        string
+	CacheMaxAge     time.Duration
+	Workers         int
+	RateBurst       int
+}
+
+// Options 実行ごとにコマンドラインで指定するオプション
+// 出力先や再開などその回限りの指定なので、ルールファイルでは読み書きしない
+type Options struct {
+	Offline        bool
+	CheckpointFile string
+	Resume         bool
+	AllCategories  bool
+	OutputDir      string
+	Format         string
+	BOM            bool
+	DatabaseFile   string
+	RunID          string
+	LegacySchema   bool
+	Strict         bool
+	Sort           string
+	Clipboard      string
+	OutputFile     string
+	LogFormat      string
+	Quiet          bool
+	Verbose        bool
 }
 
 // ParsingRules HTML解析用のルール
@@ -58,8 +87,23 @@ var (
 		BaseURL:         "https://wikiwiki.jp/sangokushi8r/",
 		MaxRetries:      3,
 		BaseDelay:       2 * time.Second,
+		MaxDelay:        time.Minute,
+		Cooldown:        5 * time.Minute,
+		MaxCooldowns:    3,
 		RequestDelay:    500 * time.Millisecond,
 		HTTPTimeout:     30 * time.Second,
+		CacheDir:        ".cache",
+		CacheMaxAge:     24 * time.Hour,
+		Workers:         4,
+		RateBurst:       1,
+	}
+
+	options = Options{
+		OutputDir: "output",
+		Format:    "json",
+		Sort:      "没年",
+		Clipboard: "auto",
+		LogFormat: "text",
 	}
 
 	rules = ParsingRules{
@@ -100,31 +144,64 @@ func (e *ProcessingError) Unwrap() error {
 	return e.Err
 }
 
+// HTTPError 200 以外のステータスコードが返された場合のエラー
+// RetryAfter はサーバーが Retry-After で指定した待ち時間（指定がなければ 0）
+type HTTPError struct {
+	URL        string
+	StatusCode int
+	Status     string
+	RetryAfter time.Duration
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("HTTPエラー: %s", e.Status)
+}
+
 // ========================================
 // 構造体定義
 // ========================================
 
+// Target 処理対象の武将（characters.json上の名前と取得先URL、元の登録項目）
+type Target struct {
+	Name  string
+	URL   string
+	Entry CharacterEntry
+}
+
 // Character 武将の情報を格納する構造体
 type Character struct {
-	Name         string `json:"名前"`
-	Reading      string `json:"読み"`
-	Azana        string `json:"字"`
-	Leadership   int    `json:"統率"`
-	Force        int    `json:"武力"`
-	Intelligence int    `json:"知力"`
-	Politics     int    `json:"政治"`
-	Charm        int    `json:"魅力"`
-	Talent       string `json:"奇才"`
-	Interest     string `json:"興味"`
-	Greed        string `json:"物欲"`
-	Loyalty      int    `json:"義理"`
-	Personality  string `json:"性格"`
-	Strategy     string `json:"戦略傾向"`
-	DeathYear    int    `json:"没年"`
-	DeathMinus13 int    `json:"没年-13"`
-	Tactics      string `json:"戦法"`
-	Skills       string `json:"特技"`
-	Fame         string `json:"重視名声"`
+	Name         string            `json:"名前"`
+	Reading      string            `json:"読み"`
+	Azana        string            `json:"字"`
+	Leadership   int               `json:"統率"`
+	Force        int               `json:"武力"`
+	Intelligence int               `json:"知力"`
+	Politics     int               `json:"政治"`
+	Charm        int               `json:"魅力"`
+	Talent       string            `json:"奇才"`
+	Interest     []string          `json:"興味"`
+	Greed        string            `json:"物欲"`
+	Loyalty      int               `json:"義理"`
+	Personality  string            `json:"性格"`
+	Strategy     string            `json:"戦略傾向"`
+	DeathYear    int               `json:"没年"`
+	DeathMinus13 int               `json:"没年-13"`
+	Tactics      []CategorizedItem `json:"戦法"`
+	Skills       []CategorizedItem `json:"特技"`
+	Fame         string            `json:"重視名声"`
+	Categories   []string          `json:"カテゴリ,omitempty"`
+	EntryName    string            `json:"登録名,omitempty"`
+	Page         string            `json:"ページ,omitempty"`
+	Aliases      []string          `json:"別名,omitempty"`
+	Tags         []string          `json:"タグ,omitempty"`
+}
+
+// CategorizedItem 所属カテゴリ付きの戦法・特技
+// Detail はページ上で括弧書きされていた補足（レベルや注記）
+type CategorizedItem struct {
+	Name     string `json:"名前"`
+	Category string `json:"カテゴリ"`
+	Detail   string `json:"詳細,omitempty"`
 }
 
 // ========================================
@@ -132,24 +209,14 @@ type Character struct {
 // ========================================
 
 func main() {
-	category, jsonFile := getCategoryAndFile()
-	characters := processCategory(category, jsonFile)
-	outputJSON(characters)
-}
-
-func getCategoryAndFile() (string, string) {
-	if len(os.Args) < 2 {
-		showAvailableCategories(config.DefaultJSONFile)
-		log.Fatal("使用方法: go run main.go <カテゴリ名> [JSONファイル]\n例: go run main.go 奇才\n例: go run main.go 奇才 test.json")
+	err := parseFlags()
+	if err == nil {
+		err = run(flag.Args())
 	}
-
-	category := os.Args[1]
-	jsonFile := config.DefaultJSONFile
-	if len(os.Args) > 2 {
-		jsonFile = os.Args[2]
+	if err != nil {
+		slog.Error(err.Error())
+		os.Exit(exitCode(err))
 	}
-
-	return category, jsonFile
 }
 
 func showAvailableCategories(jsonFile string) {
@@ -159,7 +226,7 @@ func showAvailableCategories(jsonFile string) {
 		return
 	}
 
-	var categorizedNames map[string][]string
+	var categorizedNames map[string][]CharacterEntry
 	if err := json.Unmarshal(data, &categorizedNames); err != nil {
 		fmt.Fprintf(os.Stderr, "JSONの解析に失敗しました: %v\n", err)
 		return
@@ -168,7 +235,7 @@ func showAvailableCategories(jsonFile string) {
 	showAvailableCategoriesWithData(categorizedNames)
 }
 
-func showAvailableCategoriesWithData(categorizedNames map[string][]string) {
+func showAvailableCategoriesWithData(categorizedNames map[string][]CharacterEntry) {
 	fmt.Fprintf(os.Stderr, "利用可能なカテゴリ:\n")
 	for category, names := range categorizedNames {
 		fmt.Fprintf(os.Stderr, "  %s (%d人)\n", category, len(names))
@@ -176,30 +243,160 @@ func showAvailableCategoriesWithData(categorizedNames map[string][]string) {
 	fmt.Fprintf(os.Stderr, "\n")
 }
 
-func processCategory(category, jsonFile string) []Character {
-	urls, err := loadCharactersFromJSON(category, jsonFile)
+// processCategory カテゴリの武将を取得する
+// 一部の武将の取得に失敗した場合は取得できた武将と PartialFailureError を返す
+func processCategory(category, jsonFile string) ([]Character, error) {
+	targets, err := loadCharactersFromJSON(category, jsonFile)
 	if err != nil {
-		log.Fatal("キャラクターファイルの読み込みエラー:", err)
+		return nil, fmt.Errorf("キャラクターファイルの読み込みエラー: %v", err)
 	}
 
-	var characters []Character
-	for i, url := range urls {
-		fmt.Printf("処理中 (%d/%d): %s\n", i+1, len(urls), url)
+	journal, done, err := openCheckpointJournal(checkpointPath(categoryFileName(category), jsonFile), options.Resume)
+	if err != nil {
+		return nil, fmt.Errorf("チェックポイントの読み込みエラー: %v", err)
+	}
+	defer journal.Close()
+
+	if len(done) > 0 {
+		slog.Info("チェックポイントから再開します", "done", len(done))
+	}
 
-		character, err := extractCharacterInfoWithRetry(url)
-		if err != nil {
-			handleProcessingError(url, err)
+	results, err := scrapeTargets(targets, done, journal)
+	if err != nil {
+		return nil, rateLimitAbort(err)
+	}
+	// --all と同じく所属カテゴリを記録し、serve の category= で絞り込めるようにする
+	for _, character := range results {
+		if character != nil {
+			character.Categories = []string{category}
+		}
+	}
+	if err := enforceValidation(targets, results); err != nil {
+		return collectCharacters(results), err
+	}
+	return collectCharacters(results), checkPartialFailure(results)
+}
+
+// scrapeTargets ワーカープールで武将ページを並行処理し、targets と同じ順序で結果を返す
+// 取得に失敗した武将の位置は nil になる
+// done に含まれる武将は取得せずにその結果を使い、新たに取得した武将は journal に追記する
+// レート制限エラーが発生した場合は全ワーカーを停止し、そのエラーを返す
+func scrapeTargets(targets []Target, done map[string]Character, journal *CheckpointJournal) ([]*Character, error) {
+	results := make([]*Character, len(targets))
+	var (
+		pending   []Target
+		positions []int
+	)
+	for i, target := range targets {
+		if character, ok := done[target.key()]; ok {
+			results[i] = &character
 			continue
 		}
+		pending = append(pending, target)
+		positions = append(positions, i)
+	}
+
+	progress := newProgress(len(targets), len(targets)-len(pending))
+	defer progress.Finish()
+	fetched, err := runPool(pending, progress, newCooldownPolicy(), extractCharacterInfo, func(target Target, character Character) {
+		if err := journal.Append(target.key(), character); err != nil {
+			slog.Warn("チェックポイントの書き込みエラー", "error", err)
+		}
+	})
+	for j, character := range fetched {
+		if character != nil {
+			results[positions[j]] = character
+		}
+	}
+
+	for i, character := range results {
+		if character != nil {
+			character.applyEntry(targets[i].Entry)
+		}
+	}
+	return results, err
+}
+
+// runPool ワーカープールで targets のページを fetch で並行して取得し、targets と同じ順序で結果を返す
+// 取得に失敗した位置は nil になり、成功するたびに onSuccess を呼び出す（nil なら呼び出さない）
+// progress と cooldown は呼び出し側が実行全体で共有するものを渡す
+// レート制限エラーが発生した場合は全ワーカーを停止し、そのエラーを返す
+func runPool[T any](targets []Target, progress *Progress, cooldown *cooldownPolicy, fetch func(context.Context, string) (T, error), onSuccess func(Target, T)) ([]*T, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	results := make([]*T, len(targets))
+	jobs := make(chan int)
+
+	var (
+		wg        sync.WaitGroup
+		abortOnce sync.Once
+		abortErr  error
+	)
+	abort := func(err error) {
+		abortOnce.Do(func() {
+			abortErr = err
+			cancel()
+		})
+	}
+
+	for w := 0; w < max(config.Workers, 1); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				target := targets[i]
+				progress.Begin(target)
+
+				result, err := fetchWithCooldown(ctx, target.URL, progress, cooldown, fetch)
+				if err != nil {
+					if ctx.Err() != nil {
+						return
+					}
+					progress.Complete(false)
+					if fatalErr := handleProcessingError(target.URL, err); fatalErr != nil {
+						abort(fatalErr)
+						return
+					}
+					continue
+				}
+				progress.Complete(true)
+
+				if onSuccess != nil {
+					onSuccess(target, result)
+				}
+				results[i] = &result
+			}
+		}()
+	}
 
-		characters = append(characters, character)
-		sleepBetweenRequests(i, len(urls))
+feed:
+	for i := range targets {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
 	}
+	close(jobs)
+	wg.Wait()
 
+	return results, abortErr
+}
+
+func collectCharacters(results []*Character) []Character {
+	characters := []Character{}
+	for _, character := range results {
+		if character != nil {
+			characters = append(characters, *character)
+		}
+	}
 	return characters
 }
 
-func handleProcessingError(url string, err error) {
+// handleProcessingError 処理エラーを記録する
+// クールダウンを繰り返しても解除されないレート制限エラーの場合は処理を継続できないためエラーを返す
+func handleProcessingError(url string, err error) error {
 	procErr := &ProcessingError{
 		URL:     url,
 		Message: err.Error(),
@@ -207,75 +404,93 @@ func handleProcessingError(url string, err error) {
 	}
 
 	if isRateLimitError(err) {
-		log.Fatalf("レート制限に達しました。しばらく時間を置いてから再実行してください: %v", procErr)
+		return procErr
 	}
-	log.Printf("%v", procErr)
-}
-
-func isRateLimitError(err error) bool {
-	return containsAnyString(err.Error(), rules.RetryErrors) || strings.Contains(err.Error(), "最大リトライ回数に達しました")
+	slog.Warn("武将の処理に失敗しました", "url", procErr.URL, "error", procErr.Message)
+	return nil
 }
 
-func sleepBetweenRequests(currentIndex, totalCount int) {
-	if currentIndex < totalCount-1 {
-		time.Sleep(config.RequestDelay)
+// emitCharacters 結果を出力し、--db 指定時はデータベースにも書き込む
+func emitCharacters(source string, characters []Character) error {
+	if err := outputCharacters(characters); err != nil {
+		return err
 	}
+	return exportDatabaseIfRequested(source, characters)
 }
 
-func outputJSON(characters []Character) {
-	// 没年昇順でソート
-	sort.Slice(characters, func(i, j int) bool {
-		return characters[i].DeathYear < characters[j].DeathYear
-	})
+func outputCharacters(characters []Character) error {
+	if err := sortCharacters(characters); err != nil {
+		return err
+	}
 
-	output, err := json.MarshalIndent(characters, "", "    ")
+	output, err := formatCharacters(characters, options.Format)
 	if err != nil {
-		log.Fatal("JSON変換エラー:", err)
+		return fmt.Errorf("出力変換エラー: %v", err)
 	}
 
-	jsonString := string(output)
-	fmt.Println(jsonString)
+	outputString := string(output)
+	if err := writeOutput(withBOM(output, options.Format)); err != nil {
+		return err
+	}
 
-	// クリップボードにコピー（macOSのみ）
-	if runtime.GOOS == "darwin" {
-		if err := copyToClipboard(jsonString); err != nil {
-			log.Printf("クリップボードへのコピーに失敗しました: %v", err)
+	// クリップボードにコピー（--clipboard=off または使える手段がなければ何もしない）
+	if clipboard != nil {
+		if err := clipboard.Copy(outputString); err != nil {
+			slog.Warn("クリップボードへのコピーに失敗しました", "backend", clipboard.Name(), "error", err)
 		} else {
-			fmt.Fprintf(os.Stderr, "\n結果をクリップボードにコピーしました。\n")
+			slog.Info("結果をクリップボードにコピーしました", "backend", clipboard.Name())
 		}
 	}
+	return nil
 }
 
-func copyToClipboard(text string) error {
-	cmd := exec.Command("pbcopy")
-	cmd.Stdin = strings.NewReader(text)
-	return cmd.Run()
+func sortCharacters(characters []Character) error {
+	// --sort の指定順でソート（既定は没年昇順）
+	keys, err := parseSortKeys(options.Sort)
+	if err != nil {
+		return usageErrorf("%v", err)
+	}
+	sortCharactersBy(characters, keys)
+	return nil
 }
 
-func loadCharactersFromJSON(category, jsonFile string) ([]string, error) {
-	data, err := os.ReadFile(jsonFile)
-	if err != nil {
-		return nil, fmt.Errorf("ファイル読み込みエラー: %v", err)
+func exportDatabaseIfRequested(source string, characters []Character) error {
+	if options.DatabaseFile == "" {
+		return nil
 	}
 
-	// カテゴリ別の武将名を格納するマップ
-	var categorizedNames map[string][]string
-	if err := json.Unmarshal(data, &categorizedNames); err != nil {
-		return nil, fmt.Errorf("JSON解析エラー: %v", err)
+	runID := options.RunID
+	if runID == "" {
+		runID = newRunID()
+	}
+
+	if err := exportToDatabase(options.DatabaseFile, runID, source, characters); err != nil {
+		return fmt.Errorf("データベース出力エラー: %v", err)
+	}
+	slog.Info("データベースに書き込みました", "path", options.DatabaseFile, "run_id", runID, "count", len(characters))
+	return nil
+}
+
+func loadCharactersFromJSON(category, jsonFile string) ([]Target, error) {
+	categorizedNames, err := loadCategorizedNames(jsonFile)
+	if err != nil {
+		return nil, err
 	}
 
 	// 指定されたカテゴリの武将名のみを使用
-	selectedNames, exists := categorizedNames[category]
+	selectedEntries, exists := categorizedNames[category]
 	if !exists {
 		showAvailableCategoriesWithData(categorizedNames)
 		return nil, fmt.Errorf("カテゴリ '%s' が見つかりません", category)
 	}
-	fmt.Printf("カテゴリ '%s' の武将を処理します (%d人)\n", category, len(selectedNames))
-
-	// 武将名からURLを生成
-	urls := make([]string, len(selectedNames))
-	for i, name := range selectedNames {
-		urls[i] = generateURL(name)
+	slog.Info("カテゴリの武将を処理します", "category", category, "count", len(selectedEntries))
+
+	// 武将名（ページ名の指定があればそれ）からURLを生成
+	targets := make([]Target, len(selectedEntries))
+	urls := make([]string, len(selectedEntries))
+	for i, entry := range selectedEntries {
+		targets[i] = newTarget(entry)
+		urls[i] = targets[i].URL
 	}
 
 	// 重複チェック
@@ -283,11 +498,27 @@ func loadCharactersFromJSON(category, jsonFile string) ([]string, error) {
 		return nil, fmt.Errorf("重複するURLが見つかりました: %v", duplicates)
 	}
 
-	return urls, nil
+	return targets, nil
+}
+
+// loadCategorizedNames カテゴリ別の登録項目を読み込む
+// 武将は名前の文字列とオブジェクト（CharacterEntry）のどちらで書かれていてもよい
+func loadCategorizedNames(jsonFile string) (map[string][]CharacterEntry, error) {
+	data, err := os.ReadFile(jsonFile)
+	if err != nil {
+		return nil, fmt.Errorf("ファイル読み込みエラー: %v", err)
+	}
+
+	var categorizedNames map[string][]CharacterEntry
+	if err := json.Unmarshal(data, &categorizedNames); err != nil {
+		return nil, fmt.Errorf("JSON解析エラー: %v", err)
+	}
+
+	return categorizedNames, nil
 }
 
 func generateURL(name string) string {
-	return config.BaseURL + url.QueryEscape(name)
+	return currentSite.GenerateURL(config.BaseURL, name)
 }
 
 func findDuplicateURLs(urls []string) []string {
@@ -310,83 +541,179 @@ func findDuplicateURLs(urls []string) []string {
 	return duplicates
 }
 
-func extractCharacterInfoWithRetry(url string) (Character, error) {
-	for attempt := 0; attempt < config.MaxRetries; attempt++ {
-		character, err := extractCharacterInfo(url)
-		if err == nil {
-			return character, nil
+// fetchWithCooldown fetchWithRetry でレート制限が解除されない場合は
+// 全体のクールダウンを待ってから同じページを取得し直す
+func fetchWithCooldown[T any](ctx context.Context, url string, progress *Progress, cooldown *cooldownPolicy, fetch func(context.Context, string) (T, error)) (T, error) {
+	for {
+		result, err := fetchWithRetry(ctx, url, progress.Retry, fetch)
+		if err == nil || ctx.Err() != nil || !isRateLimitError(err) || !cooldown.begin(url) {
+			return result, err
 		}
+	}
+}
 
-		if shouldRetry(err, attempt, config.MaxRetries) {
-			delay := config.BaseDelay * time.Duration(attempt+1)
-			fmt.Printf("429エラーが発生しました。%v後にリトライします... (試行 %d/%d)\n", delay, attempt+2, config.MaxRetries)
-			time.Sleep(delay)
-			continue
+// extractCharacterInfoWithRetry 一時的なエラーの場合に待機してリトライする
+// リトライのたびに onRetry を呼び出す（nil なら呼び出さない）
+func extractCharacterInfoWithRetry(ctx context.Context, url string, onRetry func()) (Character, error) {
+	return fetchWithRetry(ctx, url, onRetry, extractCharacterInfo)
+}
+
+// fetchWithRetry 一時的なエラーの場合に待機して fetch をリトライする
+// 待ち時間は retryDelay による指数バックオフで、リトライのたびに onRetry を呼び出す（nil なら呼び出さない）
+func fetchWithRetry[T any](ctx context.Context, url string, onRetry func(), fetch func(context.Context, string) (T, error)) (T, error) {
+	var zero T
+	var lastErr error
+	for attempt := 0; attempt < config.MaxRetries; attempt++ {
+		if attempt > 0 {
+			delay := retryDelay(lastErr, attempt-1)
+			if onRetry != nil {
+				onRetry()
+			}
+			slog.Warn("取得に失敗しました。リトライします", "url", url, "error", lastErr, "delay", delay.Round(time.Millisecond), "attempt", fmt.Sprintf("%d/%d", attempt+1, config.MaxRetries))
+			if err := sleepContext(ctx, delay); err != nil {
+				return zero, err
+			}
 		}
 
-		return character, err
+		result, err := fetch(ctx, url)
+		if err == nil {
+			return result, nil
+		}
+		if ctx.Err() != nil || !isRetryable(err) {
+			return zero, err
+		}
+		lastErr = err
 	}
 
-	return Character{}, fmt.Errorf("最大リトライ回数に達しました")
+	return zero, fmt.Errorf("最大リトライ回数に達しました: %w", lastErr)
 }
 
-func shouldRetry(err error, attempt, maxRetries int) bool {
-	return containsAnyString(err.Error(), rules.RetryErrors) && attempt < maxRetries-1
-}
-
-func extractCharacterInfo(url string) (Character, error) {
-	doc, err := fetchAndParseHTML(url)
+func extractCharacterInfo(ctx context.Context, url string) (Character, error) {
+	doc, err := fetchAndParseHTML(ctx, url)
 	if err != nil {
 		return Character{}, err
 	}
 
+	return currentSite.Extractor.Extract(doc), nil
+}
+
+// parseCharacter 解析済みの武将ページから武将情報を抽出する
+func parseCharacter(doc *html.Node) Character {
 	character := extractBasicInfo(doc)
 	tactics, skills := extractTacticsAndSkills(doc)
 	character.Tactics = tactics
 	character.Skills = skills
 
-	return character, nil
+	return character
+}
+
+func fetchAndParseHTML(ctx context.Context, url string) (*html.Node, error) {
+	body, err := fetchPage(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("HTMLパースエラー: %v", err)
+	}
+
+	return doc, nil
+}
+
+func fetchPage(ctx context.Context, url string) ([]byte, error) {
+	entry, cached, err := loadCachedPage(url)
+	if err != nil {
+		slog.Warn("キャッシュ読み込みエラー（無視して再取得します）", "url", url, "error", err)
+		entry, cached = nil, nil
+	}
+
+	if options.Offline {
+		if entry == nil {
+			return nil, fmt.Errorf("オフラインモードですがキャッシュが見つかりません: %s", url)
+		}
+		slog.Debug("キャッシュを使用します", "url", url, "fetched_at", entry.FetchedAt)
+		return cached, nil
+	}
+
+	if entry != nil && entry.isFresh(config.CacheMaxAge) {
+		slog.Debug("キャッシュを使用します", "url", url, "fetched_at", entry.FetchedAt)
+		return cached, nil
+	}
+
+	return downloadPage(ctx, url, entry, cached)
 }
 
-func fetchAndParseHTML(url string) (*html.Node, error) {
+func downloadPage(ctx context.Context, url string, entry *CacheEntry, cached []byte) ([]byte, error) {
+	// ネットワークアクセス時のみ共有レート制限に従う
+	if err := rateLimiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+
 	client := &http.Client{Timeout: config.HTTPTimeout}
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("リクエスト作成エラー: %v", err)
 	}
 
 	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36")
 
+	// キャッシュがある場合は条件付きリクエストで再検証する
+	if entry != nil {
+		if entry.ETag != "" {
+			req.Header.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			req.Header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("HTTPリクエストエラー: %v", err)
+		return nil, fmt.Errorf("HTTPリクエストエラー: %w", err)
 	}
 	defer resp.Body.Close()
 
+	slog.Debug("ページを取得しました", "url", url, "status", resp.StatusCode)
+	if resp.StatusCode == http.StatusNotModified && entry != nil {
+		entry.FetchedAt = time.Now()
+		if err := storeCachedPage(*entry, cached); err != nil {
+			slog.Warn("キャッシュ書き込みエラー", "url", url, "error", err)
+		}
+		return cached, nil
+	}
+
 	if err := checkHTTPStatus(resp); err != nil {
 		return nil, err
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("レスポンス読み込みエラー: %v", err)
+		return nil, fmt.Errorf("レスポンス読み込みエラー: %w", err)
 	}
 
-	doc, err := html.Parse(strings.NewReader(string(body)))
-	if err != nil {
-		return nil, fmt.Errorf("HTMLパースエラー: %v", err)
+	newEntry := CacheEntry{
+		URL:          url,
+		FetchedAt:    time.Now(),
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+	if err := storeCachedPage(newEntry, body); err != nil {
+		slog.Warn("キャッシュ書き込みエラー", "url", url, "error", err)
 	}
 
-	return doc, nil
+	return body, nil
 }
 
 func checkHTTPStatus(resp *http.Response) error {
-	if resp.StatusCode == 429 {
-		return fmt.Errorf("429 Too Many Requests")
-	}
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("HTTPエラー: %d %s", resp.StatusCode, resp.Status)
+	if resp.StatusCode != http.StatusOK {
+		return &HTTPError{
+			URL:        resp.Request.URL.String(),
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
 	}
 	return nil
 }
@@ -619,7 +946,7 @@ func isTalentTable(table *html.Node) bool {
 }
 
 func extractInterests(character *Character, doc *html.Node) {
-	var interests []string
+	interests := []string{}
 	allCells := findAllNodes(doc, "td")
 
 	for _, cell := range allCells {
@@ -635,11 +962,11 @@ func extractInterests(character *Character, doc *html.Node) {
 		interests = append(interests, text)
 	}
 
-	character.Interest = strings.Join(interests, ", ")
+	character.Interest = interests
 }
 
-func extractTacticsAndSkills(doc *html.Node) (string, string) {
-	var tactics, skills []string
+func extractTacticsAndSkills(doc *html.Node) ([]CategorizedItem, []CategorizedItem) {
+	tactics, skills := []CategorizedItem{}, []CategorizedItem{}
 
 	tables := findAllNodes(doc, "table")
 	for _, table := range tables {
@@ -651,24 +978,29 @@ func extractTacticsAndSkills(doc *html.Node) (string, string) {
 		}
 	}
 
-	return strings.Join(tactics, ", "), strings.Join(skills, ", ")
+	return tactics, skills
 }
 
-func extractFromSkillTable(table *html.Node, isCategory func(string) bool) []string {
-	var items []string
+// extractFromSkillTable 戦法・特技テーブルを上から順に走査し、直前に現れたカテゴリ見出しを各項目に付与する
+// カテゴリ見出しは th・td のどちらに置かれていても認識する
+func extractFromSkillTable(table *html.Node, isCategory func(string) bool) []CategorizedItem {
+	items := []CategorizedItem{}
+	currentCategory := ""
 
 	rows := findAllNodes(table, "tr")
 	for _, row := range rows {
-		cells := findAllNodes(row, "td")
-		for _, cell := range cells {
-			if !hasStyleWidth(cell, "70px") {
+		for _, cell := range findAllCells(row) {
+			name, detail := splitTacticSkillText(strings.TrimSpace(getNodeText(cell)))
+			if isCategory(name) {
+				currentCategory = name
 				continue
 			}
 
-			text := cleanTacticSkillText(strings.TrimSpace(getNodeText(cell)))
-			if text != "" && !isCategory(text) {
-				items = append(items, text)
+			if cell.Data != "td" || !hasStyleWidth(cell, "70px") || name == "" {
+				continue
 			}
+
+			items = append(items, CategorizedItem{Name: name, Category: currentCategory, Detail: detail})
 		}
 	}
 
@@ -696,6 +1028,41 @@ func findAllNodes(n *html.Node, tagName string) []*html.Node {
 	return nodes
 }
 
+// findNodeByID id 属性が id の要素を文書順で最初のものを返す（なければ nil）
+func findNodeByID(n *html.Node, id string) *html.Node {
+	if n.Type == html.ElementNode {
+		for _, attr := range n.Attr {
+			if attr.Key == "id" && attr.Val == id {
+				return n
+			}
+		}
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if found := findNodeByID(child, id); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// findAllCells 行内の th・td を文書順に返す
+func findAllCells(row *html.Node) []*html.Node {
+	var cells []*html.Node
+	var traverse func(*html.Node)
+
+	traverse = func(node *html.Node) {
+		if node.Type == html.ElementNode && (node.Data == "th" || node.Data == "td") {
+			cells = append(cells, node)
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			traverse(child)
+		}
+	}
+
+	traverse(row)
+	return cells
+}
+
 func findNodeWithText(n *html.Node, tagName string) *html.Node {
 	var result *html.Node
 	var traverse func(*html.Node)
@@ -777,10 +1144,16 @@ func isInterestCell(text string) bool {
 	return slices.Contains(rules.InterestItems, text)
 }
 
-func cleanTacticSkillText(text string) string {
-	// strings.Cutを使って効率的に括弧を除去
-	before, _, _ := strings.Cut(text, "(")
-	return strings.TrimSpace(before)
+// splitTacticSkillText 戦法・特技のセルを名前と括弧内の補足（レベルや注記）に分ける
+func splitTacticSkillText(text string) (string, string) {
+	// strings.Cutを使って効率的に括弧を分離
+	before, after, found := strings.Cut(text, "(")
+	if !found {
+		return strings.TrimSpace(before), ""
+	}
+
+	detail, _, _ := strings.Cut(after, ")")
+	return strings.TrimSpace(before), strings.TrimSpace(detail)
 }
 
 func containsAnyString(text string, substrings []string) bool {
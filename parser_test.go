@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+// testdata/pages のフィクスチャは実際に保存したページではなく、wikiの武将ページの構造を模して
+// 手書きしたもの。ゴールデンファイルはそれらの抽出結果を固定するだけなので、実際のサイトの
+// レイアウト変更は検出できない。実際のページで確かめるには -from-cache で置き換える
+//
+// ゴールデンファイルの再生成:
+//
+//	go test -run TestParseCharacterGolden -update
+//
+// キャッシュ済みのページでフィクスチャを更新してから再生成する:
+//
+//	go test -run TestParseCharacterGolden -from-cache .cache -update
+var (
+	updateGolden = flag.Bool("update", false, "ゴールデンファイルを現在の抽出結果で書き換える")
+	fromCache    = flag.String("from-cache", "", "指定したキャッシュディレクトリのHTMLでフィクスチャを更新する")
+)
+
+func TestParseCharacterGolden(t *testing.T) {
+	pages, err := filepath.Glob(filepath.Join("testdata", "pages", "*.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) == 0 {
+		t.Fatal("testdata/pages にフィクスチャがありません")
+	}
+
+	for _, page := range pages {
+		name := strings.TrimSuffix(filepath.Base(page), ".html")
+		t.Run(name, func(t *testing.T) {
+			if *fromCache != "" {
+				refreshFixtureFromCache(t, name, page)
+			}
+
+			body, err := os.ReadFile(page)
+			if err != nil {
+				t.Fatal(err)
+			}
+			doc, err := html.Parse(bytes.NewReader(body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := json.MarshalIndent(parseCharacter(doc), "", "    ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+
+			goldenPath := filepath.Join("testdata", "golden", name+".json")
+			if *updateGolden {
+				if err := os.WriteFile(goldenPath, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatalf("ゴールデンファイルを読み込めません (-update で生成できます): %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("抽出結果がゴールデンファイルと一致しません\n--- got\n%s\n--- want\n%s", got, want)
+			}
+		})
+	}
+}
+
+// refreshFixtureFromCache フィクスチャ名を武将名とみなし、キャッシュ済みのページで上書きする
+func refreshFixtureFromCache(t *testing.T, name, page string) {
+	t.Helper()
+
+	saved := config.CacheDir
+	config.CacheDir = *fromCache
+	defer func() { config.CacheDir = saved }()
+
+	entry, body, err := loadCachedPage(generateURL(name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry == nil {
+		t.Logf("%s はキャッシュにないため既存のフィクスチャを使います", name)
+		return
+	}
+
+	if err := os.WriteFile(page, body, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSplitTacticSkillText(t *testing.T) {
+	tests := []struct {
+		text       string
+		wantName   string
+		wantDetail string
+	}{
+		{"突破", "突破", ""},
+		{"突破(Lv3)", "突破", "Lv3"},
+		{"鼓舞 (全体)", "鼓舞", "全体"},
+		{"教練(政治", "教練", "政治"},
+	}
+
+	for _, tt := range tests {
+		name, detail := splitTacticSkillText(tt.text)
+		if name != tt.wantName || detail != tt.wantDetail {
+			t.Errorf("splitTacticSkillText(%q) = %q, %q; want %q, %q", tt.text, name, detail, tt.wantName, tt.wantDetail)
+		}
+	}
+}
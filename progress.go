@@ -0,0 +1,231 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ========================================
+// 進捗表示
+// ========================================
+
+// progressRedrawInterval 進捗バーを再描画する最短間隔
+const progressRedrawInterval = 100 * time.Millisecond
+
+const progressBarWidth = 30
+
+// Progress 武将ページ取得の進捗を集計して表示する
+// 標準エラー出力が端末なら1行の進捗バーを書き換え、そうでなければ1件ごとにログを出す
+type Progress struct {
+	mu          sync.Mutex
+	status      *statusLineWriter
+	interactive bool
+	now         func() time.Time
+
+	total     int
+	resumed   int
+	started   int
+	processed int
+	failed    int
+	retries   int
+	start     time.Time
+	lastDraw  time.Time
+}
+
+// newProgress total 人の取得を始める（resumed 人はチェックポイントから復元済み）
+func newProgress(total, resumed int) *Progress {
+	p := &Progress{
+		status:      stderrStatus,
+		interactive: isTerminal(os.Stderr) && !options.Quiet,
+		now:         time.Now,
+		total:       total,
+		resumed:     resumed,
+	}
+	p.start = p.now()
+	return p
+}
+
+// Add 取得する件数を n 件増やす（巡回中に取得先が見つかった場合）
+func (p *Progress) Add(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.total += n
+	p.draw()
+}
+
+// Begin 1件の取得を開始したことを記録する
+func (p *Progress) Begin(target Target) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// 複数のワーカーが同時に取得するため、完了数ではなく開始した件数で番号を付ける
+	p.started++
+	attrs := []any{"progress", fmt.Sprintf("%d/%d", p.resumed+p.started, p.total), "url", target.URL}
+	if p.interactive {
+		// 進捗バーに集計が出ているので1件ごとのログはデバッグ扱いにする
+		slog.Debug("処理中", attrs...)
+		p.draw()
+		return
+	}
+	slog.Info("処理中", append(attrs, p.statsAttrs()...)...)
+}
+
+// Complete 1件の取得が終わったことを記録する
+func (p *Progress) Complete(ok bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.processed++
+	if !ok {
+		p.failed++
+	}
+	p.draw()
+}
+
+// Retry 取得のリトライが発生したことを記録する
+func (p *Progress) Retry() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.retries++
+	p.draw()
+}
+
+// Finish 進捗バーを消して集計結果をログに出す
+func (p *Progress) Finish() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.interactive {
+		p.status.Clear()
+	}
+	slog.Info("取得が完了しました",
+		"done", p.done(), "total", p.total, "failed", p.failed, "retries", p.retries,
+		"elapsed", p.now().Sub(p.start).Round(time.Second))
+}
+
+func (p *Progress) done() int {
+	return p.resumed + p.processed
+}
+
+// throughput チェックポイントから復元した分を除いた1秒あたりの処理件数
+func (p *Progress) throughput() float64 {
+	elapsed := p.now().Sub(p.start).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(p.processed) / elapsed
+}
+
+// eta 現在の処理速度で残りを処理し終えるまでの見込み時間（見積もれない場合は負）
+func (p *Progress) eta() time.Duration {
+	rate := p.throughput()
+	if rate <= 0 {
+		return -1
+	}
+	remaining := p.total - p.done()
+	return time.Duration(float64(remaining) / rate * float64(time.Second)).Round(time.Second)
+}
+
+func (p *Progress) statsAttrs() []any {
+	return []any{
+		"failed", p.failed,
+		"retries", p.retries,
+		"rate", fmt.Sprintf("%.2f/s", p.throughput()),
+		"eta", formatETA(p.eta()),
+	}
+}
+
+// draw 進捗バーを描画する（前回から間隔が空いていない場合は最後の1件以外は省略する）
+func (p *Progress) draw() {
+	if !p.interactive {
+		return
+	}
+	now := p.now()
+	if now.Sub(p.lastDraw) < progressRedrawInterval && p.done() < p.total {
+		return
+	}
+	p.lastDraw = now
+	p.status.SetStatus(p.line())
+}
+
+// line "[#####-----] 12/40 失敗 1 リトライ 2 0.80件/秒 残り 35s" 形式の進捗行
+func (p *Progress) line() string {
+	filled := 0
+	if p.total > 0 {
+		filled = progressBarWidth * p.done() / p.total
+	}
+	bar := strings.Repeat("#", filled) + strings.Repeat("-", progressBarWidth-filled)
+	return fmt.Sprintf("[%s] %d/%d 失敗 %d リトライ %d %.2f件/秒 残り %s",
+		bar, p.done(), p.total, p.failed, p.retries, p.throughput(), formatETA(p.eta()))
+}
+
+func formatETA(d time.Duration) string {
+	if d < 0 {
+		return "不明"
+	}
+	return d.String()
+}
+
+// isTerminal ファイルが端末かどうか
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// ----------------------------------------
+// ステータス行
+// ----------------------------------------
+
+// statusLineWriter 末尾に書き換え可能なステータス行（進捗バー）を持つ出力
+// ログを書く前にステータス行を消し、書いた後に描き直すことで表示が混ざらないようにする
+type statusLineWriter struct {
+	mu     sync.Mutex
+	out    io.Writer
+	status string
+}
+
+// stderrStatus ログと進捗バーが共有する標準エラー出力
+var stderrStatus = &statusLineWriter{out: os.Stderr}
+
+const clearLine = "\r\x1b[K"
+
+func (w *statusLineWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.status != "" {
+		io.WriteString(w.out, clearLine)
+	}
+	n, err := w.out.Write(b)
+	if w.status != "" {
+		io.WriteString(w.out, w.status)
+	}
+	return n, err
+}
+
+// SetStatus ステータス行を書き換える
+func (w *statusLineWriter) SetStatus(status string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.status = status
+	io.WriteString(w.out, clearLine+status)
+}
+
+// Clear ステータス行を消す
+func (w *statusLineWriter) Clear() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.status != "" {
+		io.WriteString(w.out, clearLine)
+		w.status = ""
+	}
+}
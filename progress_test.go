@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProgressLine(t *testing.T) {
+	var buf bytes.Buffer
+	clock := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	p := &Progress{
+		status:      &statusLineWriter{out: &buf},
+		interactive: true,
+		now:         func() time.Time { return clock },
+		total:       10,
+		resumed:     2,
+	}
+	p.start = clock
+
+	if got := p.line(); !strings.Contains(got, "2/10") || !strings.HasSuffix(got, "残り 不明") {
+		t.Errorf("開始直後の進捗行 = %q", got)
+	}
+
+	clock = clock.Add(4 * time.Second)
+	p.Complete(true)
+	p.Retry()
+	clock = clock.Add(4 * time.Second)
+	p.Complete(false)
+
+	// 8秒で2件処理したので 0.25件/秒、残り6件で24秒
+	want := "[############------------------] 4/10 失敗 1 リトライ 1 0.25件/秒 残り 24s"
+	if got := p.line(); got != want {
+		t.Errorf("got %q; want %q", got, want)
+	}
+	if !strings.HasSuffix(buf.String(), clearLine+want) {
+		t.Errorf("ステータス行が描画されていません: %q", buf.String())
+	}
+}
+
+func TestStatusLineWriterRedrawsAfterLog(t *testing.T) {
+	var buf bytes.Buffer
+	w := &statusLineWriter{out: &buf}
+
+	w.SetStatus("[---] 0/3")
+	w.Write([]byte("ログ\n"))
+	w.Clear()
+
+	want := clearLine + "[---] 0/3" + clearLine + "ログ\n" + "[---] 0/3" + clearLine
+	if buf.String() != want {
+		t.Errorf("got %q; want %q", buf.String(), want)
+	}
+}
+
+func TestProgressBeginNumbersStartedFetches(t *testing.T) {
+	saved := slog.Default()
+	defer slog.SetDefault(saved)
+	var buf bytes.Buffer
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+
+	p := &Progress{status: &statusLineWriter{}, now: time.Now, total: 3}
+	// 3つのワーカーがどれも完了する前に取得を始めた場合
+	for _, name := range []string{"曹操", "劉備", "孫権"} {
+		p.Begin(Target{Name: name})
+	}
+
+	for _, want := range []string{"progress=1/3", "progress=2/3", "progress=3/3"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("%s が出力されていません:\n%s", want, buf.String())
+		}
+	}
+}
@@ -0,0 +1,357 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ========================================
+// 絞り込み式
+// ========================================
+//
+// 例: 武力 >= 90 and 性格 == 猪突 and 没年 > 220
+//     (戦法 contains 騎兵 or 特技 contains 看破) and not 奇才 == ""
+//
+// 項目名は JSON タグ名（統率）と Go のフィールド名（Leadership）のどちらでも指定できる。
+// 一覧項目（戦法・特技・興味・カテゴリ）は contains で要素を検索し、
+// 戦法・特技は名前とカテゴリのどちらにも一致する。
+
+// characterPredicate 武将が条件を満たすかどうかを判定する
+type characterPredicate func(Character) bool
+
+// runQueryCommand query サブコマンドを実行する
+func runQueryCommand(args []string) error {
+	fs := flag.NewFlagSet("query", flag.ExitOnError)
+	dbPath := fs.String("db", "", "結果ファイルの代わりに読み込むデータベースファイル")
+	runID := fs.String("run-id", "", "--db から読み込むスナップショットの run ID（省略時は最新）")
+	sortSpec := fs.String("sort", "", "並び順の項目（カンマ区切り、先頭に - で降順。例: -武力,没年）")
+	limit := fs.Int("limit", 0, "出力する最大人数（0で無制限）")
+	fs.StringVar(&options.Format, "format", options.Format, "出力形式 ("+strings.Join(outputFormats, "|")+")")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "使用方法: go run main.go query [オプション] <式> <結果ファイル>\n        go run main.go query [オプション] --db <データベースファイル> <式>\n例: go run main.go query '武力 >= 90 and 性格 == 猪突' output/all.json\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	var (
+		characters []Character
+		err        error
+	)
+	switch {
+	case *dbPath != "" && fs.NArg() == 1:
+		characters, err = loadFromDatabase(*dbPath, *runID)
+	case *dbPath == "" && fs.NArg() == 2:
+		characters, err = loadCharactersFile(fs.Arg(1))
+	default:
+		fs.Usage()
+		return usageErrorf("式と結果ファイル（または --db）を指定してください")
+	}
+	if err != nil {
+		return err
+	}
+
+	predicate, err := parseQuery(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	keys, err := parseSortKeys(*sortSpec)
+	if err != nil {
+		return err
+	}
+	if err := validateSettings(); err != nil {
+		return err
+	}
+
+	var matched []Character
+	for _, character := range characters {
+		if predicate(character) {
+			matched = append(matched, character)
+		}
+	}
+	sortCharactersBy(matched, keys)
+	if *limit > 0 && len(matched) > *limit {
+		matched = matched[:*limit]
+	}
+
+	output, err := formatCharacters(matched, options.Format)
+	if err != nil {
+		return err
+	}
+	return writeOutput(withBOM(output, options.Format))
+}
+
+// parseQuery 絞り込み式を解析して判定関数を返す
+func parseQuery(source string) (characterPredicate, error) {
+	tokens, err := tokenizeQuery(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &queryParser{tokens: tokens}
+	predicate, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, fmt.Errorf("式の %d 文字目に余分な %q があります", tok.pos+1, tok.text)
+	}
+	return predicate, nil
+}
+
+// ----------------------------------------
+// 字句解析
+// ----------------------------------------
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenString
+	tokenOperator
+	tokenLParen
+	tokenRParen
+)
+
+type queryToken struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+var queryOperators = []string{"==", "!=", ">=", "<=", "&&", "||", ">", "<", "=", "!"}
+
+func tokenizeQuery(source string) ([]queryToken, error) {
+	var tokens []queryToken
+	// エラー表示用の位置はバイト数ではなく文字数で数える
+	at := func(pos int) int { return utf8.RuneCountInString(source[:pos]) }
+
+	for pos := 0; pos < len(source); {
+		r, size := utf8.DecodeRuneInString(source[pos:])
+		switch {
+		case unicode.IsSpace(r):
+			pos += size
+		case r == '(':
+			tokens = append(tokens, queryToken{tokenLParen, "(", at(pos)})
+			pos += size
+		case r == ')':
+			tokens = append(tokens, queryToken{tokenRParen, ")", at(pos)})
+			pos += size
+		case r == '"' || r == '\'':
+			end := strings.IndexRune(source[pos+size:], r)
+			if end < 0 {
+				return nil, fmt.Errorf("式の %d 文字目の文字列が閉じられていません", at(pos)+1)
+			}
+			tokens = append(tokens, queryToken{tokenString, source[pos+size : pos+size+end], at(pos)})
+			pos += size + end + size
+		case isQueryWordRune(r) || (r == '-' && pos+size < len(source) && isDigit(source[pos+size])):
+			start := pos
+			pos += size
+			for pos < len(source) {
+				r, size := utf8.DecodeRuneInString(source[pos:])
+				// 没年-13 のように項目名の途中に - を含められる
+				if !isQueryWordRune(r) && r != '-' {
+					break
+				}
+				pos += size
+			}
+			tokens = append(tokens, queryToken{tokenWord, source[start:pos], at(start)})
+		default:
+			matched := false
+			for _, op := range queryOperators {
+				if strings.HasPrefix(source[pos:], op) {
+					tokens = append(tokens, queryToken{tokenOperator, op, at(pos)})
+					pos += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("式の %d 文字目に不明な文字 %q があります", at(pos)+1, r)
+			}
+		}
+	}
+
+	return append(tokens, queryToken{tokenEOF, "", at(len(source))}), nil
+}
+
+func isQueryWordRune(r rune) bool {
+	return r == '_' || r == '.' || unicode.IsLetter(r) || unicode.IsDigit(r) || r > unicode.MaxASCII && !unicode.IsSpace(r) && !unicode.IsPunct(r)
+}
+
+func isDigit(b byte) bool {
+	return '0' <= b && b <= '9'
+}
+
+// ----------------------------------------
+// 構文解析
+// ----------------------------------------
+
+type queryParser struct {
+	tokens []queryToken
+	pos    int
+}
+
+func (p *queryParser) peek() queryToken {
+	return p.tokens[p.pos]
+}
+
+func (p *queryParser) next() queryToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *queryParser) acceptKeyword(words ...string) bool {
+	tok := p.peek()
+	for _, word := range words {
+		if (tok.kind == tokenWord && strings.EqualFold(tok.text, word)) || (tok.kind == tokenOperator && tok.text == word) {
+			p.pos++
+			return true
+		}
+	}
+	return false
+}
+
+func (p *queryParser) parseOr() (characterPredicate, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword("or", "||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(c Character) bool { return l(c) || right(c) }
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseAnd() (characterPredicate, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword("and", "&&") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(c Character) bool { return l(c) && right(c) }
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseUnary() (characterPredicate, error) {
+	if p.acceptKeyword("not", "!") {
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return func(c Character) bool { return !inner(c) }, nil
+	}
+
+	if p.peek().kind == tokenLParen {
+		p.next()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if tok := p.next(); tok.kind != tokenRParen {
+			return nil, fmt.Errorf("式の %d 文字目に ) が必要です", tok.pos+1)
+		}
+		return inner, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *queryParser) parseComparison() (characterPredicate, error) {
+	fieldToken := p.next()
+	if fieldToken.kind != tokenWord {
+		return nil, fmt.Errorf("式の %d 文字目に項目名が必要です", fieldToken.pos+1)
+	}
+	field, ok := lookupCharacterField(fieldToken.text)
+	if !ok {
+		return nil, fmt.Errorf("不明な項目です: %s", fieldToken.text)
+	}
+
+	opToken := p.next()
+	op := opToken.text
+	switch {
+	case opToken.kind == tokenWord && strings.EqualFold(op, "contains"):
+		op = "contains"
+	case opToken.kind == tokenOperator && op == "=":
+		op = "=="
+	case opToken.kind != tokenOperator || op == "!" || op == "&&" || op == "||":
+		return nil, fmt.Errorf("式の %d 文字目に比較演算子が必要です", opToken.pos+1)
+	}
+
+	valueToken := p.next()
+	if valueToken.kind != tokenWord && valueToken.kind != tokenString {
+		return nil, fmt.Errorf("式の %d 文字目に値が必要です", valueToken.pos+1)
+	}
+
+	return compileComparison(field, op, valueToken.text)
+}
+
+func compileComparison(field characterField, op, value string) (characterPredicate, error) {
+	switch {
+	case field.isList():
+		if op != "contains" {
+			return nil, fmt.Errorf("一覧項目 %s には contains を使ってください", field.Name)
+		}
+		return func(c Character) bool { return listContains(field.value(c), value) }, nil
+
+	case field.Kind == reflect.Int:
+		want, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s には数値を指定してください: %q", field.Name, value)
+		}
+		compare, err := comparisonFunc(op)
+		if err != nil {
+			return nil, err
+		}
+		return func(c Character) bool { return compare(int(field.value(c).Int()) - want) }, nil
+
+	default:
+		if op == "contains" {
+			return func(c Character) bool { return strings.Contains(field.value(c).String(), value) }, nil
+		}
+		compare, err := comparisonFunc(op)
+		if err != nil {
+			return nil, err
+		}
+		return func(c Character) bool { return compare(strings.Compare(field.value(c).String(), value)) }, nil
+	}
+}
+
+// comparisonFunc 比較結果（負・0・正）を演算子に従って判定する関数を返す
+func comparisonFunc(op string) (func(int) bool, error) {
+	switch op {
+	case "==":
+		return func(d int) bool { return d == 0 }, nil
+	case "!=":
+		return func(d int) bool { return d != 0 }, nil
+	case ">":
+		return func(d int) bool { return d > 0 }, nil
+	case ">=":
+		return func(d int) bool { return d >= 0 }, nil
+	case "<":
+		return func(d int) bool { return d < 0 }, nil
+	case "<=":
+		return func(d int) bool { return d <= 0 }, nil
+	default:
+		return nil, fmt.Errorf("演算子 %s はこの項目に使えません", op)
+	}
+}
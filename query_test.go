@@ -0,0 +1,66 @@
+package main
+
+import "testing"
+
+func TestParseQuery(t *testing.T) {
+	characters := []Character{
+		{Name: "曹操", Reading: "そうそう", Force: 72, DeathYear: 220, Personality: "冷静", Categories: []string{"奇才"},
+			Tactics: []CategorizedItem{{Name: "突破", Category: "騎兵"}}},
+		{Name: "張飛", Reading: "ちょうひ", Force: 98, DeathYear: 221, Personality: "猪突",
+			Tactics: []CategorizedItem{{Name: "突撃", Category: "歩兵"}}},
+		{Name: "蔡琰", Reading: "さいえん", Force: 9, DeathYear: 250, Personality: "温和", Categories: []string{"女性"}},
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"武力 >= 90", []string{"張飛"}},
+		{"Force < 90 and not 性格 == 冷静", []string{"蔡琰"}},
+		{"戦法 contains 騎兵 or 戦法 contains 突撃", []string{"曹操", "張飛"}},
+		{"(没年 > 220 && 武力 < 50) || カテゴリ contains 奇才", []string{"曹操", "蔡琰"}},
+		{`読み contains "ちょう"`, []string{"張飛"}},
+		{"没年 != 220", []string{"張飛", "蔡琰"}},
+	}
+
+	for _, tt := range tests {
+		predicate, err := parseQuery(tt.query)
+		if err != nil {
+			t.Errorf("%s: %v", tt.query, err)
+			continue
+		}
+
+		var got []string
+		for _, character := range characters {
+			if predicate(character) {
+				got = append(got, character.Name)
+			}
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: got %v; want %v", tt.query, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: got %v; want %v", tt.query, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	for _, query := range []string{
+		"",
+		"武力 >= ",
+		"武力 >= 強い",
+		"興味 == 書物",
+		"(武力 > 1",
+		"身長 > 180",
+		"性格 == '猪突",
+	} {
+		if _, err := parseQuery(query); err == nil {
+			t.Errorf("%q: エラーになりませんでした", query)
+		}
+	}
+}
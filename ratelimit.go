@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ========================================
+// レート制限
+// ========================================
+
+// RateLimiter 全ワーカーで共有するトークンバケット
+// interval ごとにトークンが1つ補充され、最大 burst 個まで貯まる
+type RateLimiter struct {
+	mu          sync.Mutex
+	interval    time.Duration
+	burst       float64
+	tokens      float64
+	last        time.Time
+	pausedUntil time.Time
+}
+
+var rateLimiter = newRateLimiter(config.RequestDelay, config.RateBurst)
+
+func newRateLimiter(interval time.Duration, burst int) *RateLimiter {
+	burst = max(burst, 1)
+	return &RateLimiter{
+		interval: interval,
+		burst:    float64(burst),
+		tokens:   float64(burst),
+		last:     time.Now(),
+	}
+}
+
+// Wait トークンを1つ取得できるまで待機する
+// クールダウン中はその終了まで待ってからトークンを取得する
+// 待機中にコンテキストがキャンセルされた場合はそのエラーを返す
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	for pause := l.pauseRemaining(); pause > 0; pause = l.pauseRemaining() {
+		if err := sleepContext(ctx, pause); err != nil {
+			return err
+		}
+	}
+
+	delay := l.reserve()
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	return sleepContext(ctx, delay)
+}
+
+// Pause 全ワーカーのリクエストを d の間停止する（クールダウン）
+func (l *RateLimiter) Pause(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if until := time.Now().Add(d); until.After(l.pausedUntil) {
+		l.pausedUntil = until
+	}
+}
+
+// pauseRemaining クールダウンの残り時間
+func (l *RateLimiter) pauseRemaining() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return time.Until(l.pausedUntil)
+}
+
+// reserve トークンを1つ予約し、使用可能になるまでの待ち時間を返す
+func (l *RateLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.interval <= 0 {
+		return 0
+	}
+
+	now := time.Now()
+	l.tokens = min(l.burst, l.tokens+float64(now.Sub(l.last))/float64(l.interval))
+	l.last = now
+
+	// 不足分は負のトークンとして前借りし、後続の呼び出しを順番に待たせる
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens * float64(l.interval))
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
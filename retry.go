@@ -0,0 +1,128 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ========================================
+// リトライとバックオフ
+// ========================================
+
+// jitterFraction 待ち時間を [d*(1-jitterFraction), d] の範囲でばらつかせる
+// 複数のワーカーが同時に失敗しても一斉に再送しないようにする
+const jitterFraction = 0.5
+
+// randomFloat ジッター用の乱数（テストで差し替える）
+var randomFloat = rand.Float64
+
+// isRetryable 時間を置けば成功する可能性のあるエラーかどうか
+// 429・5xx の HTTP エラー、タイムアウト・接続のリセットや拒否・応答の途切れ、
+// rules.RetryErrors に一致するエラーをリトライ対象とする
+// *url.Error はすべて net.Error を満たすため、未対応のスキームや名前解決・証明書の失敗のような
+// 恒久的なエラーと区別できるよう Timeout() と個別のエラーで判定する
+func isRetryable(err error) bool {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == http.StatusTooManyRequests || httpErr.StatusCode >= 500
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	return containsAnyString(err.Error(), rules.RetryErrors)
+}
+
+// isRateLimitError サーバーからリクエストの抑制を求められたエラーかどうか
+func isRateLimitError(err error) bool {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == http.StatusTooManyRequests
+	}
+	return containsAnyString(err.Error(), rules.RetryErrors)
+}
+
+// retryDelay attempt 回目（0始まり）の失敗後に待つ時間
+// BaseDelay を起点に指数的に増やして MaxDelay で打ち切り（0 なら上限なし）、ジッターを加える
+// サーバーが Retry-After を指定した場合はそれより短くしない
+func retryDelay(err error, attempt int) time.Duration {
+	delay := config.BaseDelay
+	for i := 0; i < attempt; i++ {
+		if config.MaxDelay > 0 && delay >= config.MaxDelay || delay > math.MaxInt64/2 {
+			break
+		}
+		delay *= 2
+	}
+	if config.MaxDelay > 0 {
+		delay = min(delay, config.MaxDelay)
+	}
+	delay -= time.Duration(float64(delay) * jitterFraction * randomFloat())
+
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) && httpErr.RetryAfter > delay {
+		delay = httpErr.RetryAfter
+	}
+	return delay
+}
+
+// cooldownPolicy リトライしてもレート制限が解けない場合に実行全体を一時停止する方針
+// 停止は1回の実行で config.MaxCooldowns 回まで行い、それを超えたら処理を中断する
+type cooldownPolicy struct {
+	mu        sync.Mutex
+	remaining int
+}
+
+func newCooldownPolicy() *cooldownPolicy {
+	return &cooldownPolicy{remaining: config.MaxCooldowns}
+}
+
+// begin 全ワーカーのクールダウンを開始する
+// 他のワーカーがすでに開始している場合はそれに合流する
+// 上限に達していて再開できない場合は false を返す
+func (c *cooldownPolicy) begin(url string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if rateLimiter.pauseRemaining() > 0 {
+		return true
+	}
+	if c.remaining <= 0 {
+		return false
+	}
+
+	c.remaining--
+	rateLimiter.Pause(config.Cooldown)
+	slog.Warn("レート制限が解除されないため全体を一時停止します", "url", url, "cooldown", config.Cooldown, "remaining", c.remaining)
+	return true
+}
+
+// parseRetryAfter Retry-After ヘッダー（秒数または HTTP 日付）を待ち時間に変換する
+// 解釈できない場合や過去の日時の場合は 0 を返す
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(at.Sub(now), 0)
+	}
+	return 0
+}
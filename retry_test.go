@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRetryDelay(t *testing.T) {
+	savedConfig, savedRandom := config, randomFloat
+	defer func() { config, randomFloat = savedConfig, savedRandom }()
+
+	timeout := errors.New("timeout")
+	tooMany := &HTTPError{StatusCode: http.StatusTooManyRequests, RetryAfter: 30 * time.Second}
+	tests := []struct {
+		name     string
+		maxDelay time.Duration
+		err      error
+		attempt  int
+		jitter   float64
+		want     time.Duration
+	}{
+		{"初回", 5 * time.Second, timeout, 0, 0, time.Second},
+		{"倍々に増やす", 5 * time.Second, timeout, 2, 0, 4 * time.Second},
+		{"上限で打ち切る", 5 * time.Second, timeout, 3, 0, 5 * time.Second},
+		{"上限を超えたあとも上限のまま", 5 * time.Second, timeout, 10, 0, 5 * time.Second},
+		{"上限なしでも倍々に増やす", 0, timeout, 3, 0, 8 * time.Second},
+		{"上限なしでも桁あふれしない", 0, timeout, 100, 0, time.Second << 33},
+		{"ジッターは最大で半分まで短くする", 5 * time.Second, timeout, 1, 1, time.Second},
+		{"Retry-After がバックオフより長ければ優先する", 5 * time.Second, tooMany, 0, 0, 30 * time.Second},
+		{"上限なしでも Retry-After より短くしない", 0, tooMany, 2, 1, 30 * time.Second},
+		{"バックオフが Retry-After より長ければそちらを使う", 0, tooMany, 6, 0, 64 * time.Second},
+	}
+	for _, tt := range tests {
+		config.BaseDelay = time.Second
+		config.MaxDelay = tt.maxDelay
+		randomFloat = func() float64 { return tt.jitter }
+		if got := retryDelay(tt.err, tt.attempt); got != tt.want {
+			t.Errorf("%s: got %v; want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"120", 2 * time.Minute},
+		{"Mon, 01 Jan 2024 00:00:30 GMT", 30 * time.Second},
+		{"Sun, 31 Dec 2023 23:59:00 GMT", 0},
+		{"", 0},
+		{"しばらく", 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value, now); got != tt.want {
+			t.Errorf("%q: got %v; want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&HTTPError{StatusCode: 429}, true},
+		{&HTTPError{StatusCode: 503}, true},
+		{&HTTPError{StatusCode: 404}, false},
+		{&ProcessingError{Err: &HTTPError{StatusCode: 502}}, true},
+		{errors.New("HTMLパースエラー"), false},
+		{&url.Error{Op: "Get", URL: "ftp://example.com/", Err: errors.New(`unsupported protocol scheme "ftp"`)}, false},
+		{&url.Error{Op: "Get", URL: "https://example.invalid/", Err: &net.DNSError{Err: "no such host", Name: "example.invalid", IsNotFound: true}}, false},
+		{&url.Error{Op: "Get", URL: "https://example.com/", Err: &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}}, true},
+		{&url.Error{Op: "Get", URL: "https://example.com/", Err: &net.DNSError{Err: "i/o timeout", IsTimeout: true}}, true},
+		{fmt.Errorf("本文の読み込みエラー: %w", io.ErrUnexpectedEOF), true},
+	}
+	for _, tt := range tests {
+		if got := isRetryable(tt.err); got != tt.want {
+			t.Errorf("%v: got %v; want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+// useTestFetchConfig キャッシュとレート制限を無効にし、待ち時間を短くした設定に差し替える
+func useTestFetchConfig(t *testing.T) {
+	savedConfig, savedLimiter := config, rateLimiter
+	t.Cleanup(func() { config, rateLimiter = savedConfig, savedLimiter })
+
+	config.CacheDir = t.TempDir()
+	config.CacheMaxAge = 0
+	config.BaseDelay = time.Millisecond
+	config.MaxDelay = 5 * time.Millisecond
+	config.Cooldown = 10 * time.Millisecond
+	config.MaxRetries = 3
+	rateLimiter = newRateLimiter(0, 1)
+}
+
+func TestExtractCharacterInfoWithRetryRecoversFrom5xx(t *testing.T) {
+	useTestFetchConfig(t)
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("<html><body></body></html>"))
+	}))
+	defer server.Close()
+
+	retries := 0
+	if _, err := extractCharacterInfoWithRetry(context.Background(), server.URL, func() { retries++ }); err != nil {
+		t.Fatal(err)
+	}
+	if retries != 2 || requests.Load() != 3 {
+		t.Errorf("retries = %d, requests = %d; want 2, 3", retries, requests.Load())
+	}
+}
+
+func TestFetchWithCooldown(t *testing.T) {
+	useTestFetchConfig(t)
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	config.MaxCooldowns = 1
+	progress := &Progress{status: &statusLineWriter{}, now: time.Now}
+	_, err := fetchWithCooldown(context.Background(), server.URL, progress, newCooldownPolicy(), extractCharacterInfo)
+
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("err = %v; want 429 HTTPError", err)
+	}
+	// クールダウン1回を挟んで MaxRetries 回ずつ取得する
+	if requests.Load() != 6 || progress.retries != 4 {
+		t.Errorf("requests = %d, retries = %d; want 6, 4", requests.Load(), progress.retries)
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ========================================
+// ルールファイル
+// ========================================
+
+// RulesFile ルールファイルの構造
+// 記載されていない項目は組み込みの既定値のまま使われる
+type RulesFile struct {
+	Config Config       `json:"config"`
+	Rules  ParsingRules `json:"rules"`
+}
+
+// jsonDuration "500ms" や "2s" のような文字列で表す時間
+type jsonDuration time.Duration
+
+func (d jsonDuration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *jsonDuration) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return fmt.Errorf("時間は \"500ms\" のような文字列で指定してください: %s", data)
+	}
+	parsed, err := time.ParseDuration(text)
+	if err != nil {
+		return fmt.Errorf("時間の形式が不正です: %q", text)
+	}
+	*d = jsonDuration(parsed)
+	return nil
+}
+
+// configAlias Config のメソッドを持たない別名（MarshalJSON の再帰呼び出しを避ける）
+type configAlias Config
+
+// configJSON 時間項目を文字列で表した Config の JSON 表現
+// 外側のフィールドが埋め込まれた同名フィールドより優先される
+type configJSON struct {
+	*configAlias
+	BaseDelay    jsonDuration
+	MaxDelay     jsonDuration
+	Cooldown     jsonDuration
+	RequestDelay jsonDuration
+	HTTPTimeout  jsonDuration
+	CacheMaxAge  jsonDuration
+}
+
+func (c Config) MarshalJSON() ([]byte, error) {
+	return json.Marshal(configJSON{
+		configAlias:  (*configAlias)(&c),
+		BaseDelay:    jsonDuration(c.BaseDelay),
+		MaxDelay:     jsonDuration(c.MaxDelay),
+		Cooldown:     jsonDuration(c.Cooldown),
+		RequestDelay: jsonDuration(c.RequestDelay),
+		HTTPTimeout:  jsonDuration(c.HTTPTimeout),
+		CacheMaxAge:  jsonDuration(c.CacheMaxAge),
+	})
+}
+
+func (c *Config) UnmarshalJSON(data []byte) error {
+	aux := configJSON{
+		configAlias:  (*configAlias)(c),
+		BaseDelay:    jsonDuration(c.BaseDelay),
+		MaxDelay:     jsonDuration(c.MaxDelay),
+		Cooldown:     jsonDuration(c.Cooldown),
+		RequestDelay: jsonDuration(c.RequestDelay),
+		HTTPTimeout:  jsonDuration(c.HTTPTimeout),
+		CacheMaxAge:  jsonDuration(c.CacheMaxAge),
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&aux); err != nil {
+		return err
+	}
+
+	c.BaseDelay = time.Duration(aux.BaseDelay)
+	c.MaxDelay = time.Duration(aux.MaxDelay)
+	c.Cooldown = time.Duration(aux.Cooldown)
+	c.RequestDelay = time.Duration(aux.RequestDelay)
+	c.HTTPTimeout = time.Duration(aux.HTTPTimeout)
+	c.CacheMaxAge = time.Duration(aux.CacheMaxAge)
+	return nil
+}
+
+// loadRulesFile ルールファイルを読み込み、現在の config と rules に上書きする
+func loadRulesFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("ルールファイル読み込みエラー: %v", err)
+	}
+
+	merged := RulesFile{Config: config, Rules: rules}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&merged); err != nil {
+		return fmt.Errorf("ルールファイル %s の解析エラー: %v", path, err)
+	}
+
+	config = merged.Config
+	rules = merged.Rules
+	return nil
+}
+
+// validateSettings 設定値とルールの整合性を検証する
+func validateSettings() error {
+	var errs []error
+
+	if !strings.HasSuffix(config.BaseURL, "/") {
+		errs = append(errs, fmt.Errorf("config.BaseURL は / で終わる必要があります: %q", config.BaseURL))
+	}
+	if config.MaxRetries < 1 {
+		errs = append(errs, fmt.Errorf("config.MaxRetries は1以上にしてください: %d", config.MaxRetries))
+	}
+	if config.MaxCooldowns < 0 {
+		errs = append(errs, fmt.Errorf("config.MaxCooldowns に負の値は指定できません: %d", config.MaxCooldowns))
+	}
+	if config.Workers < 1 {
+		errs = append(errs, fmt.Errorf("config.Workers は1以上にしてください: %d", config.Workers))
+	}
+	if !slices.Contains(outputFormats, options.Format) {
+		errs = append(errs, fmt.Errorf("不明な出力形式です: %s (%s のいずれかを指定してください)", options.Format, strings.Join(outputFormats, ", ")))
+	}
+	if !slices.Contains(logFormats, options.LogFormat) {
+		errs = append(errs, fmt.Errorf("不明なログ形式です: %s (%s のいずれかを指定してください)", options.LogFormat, strings.Join(logFormats, ", ")))
+	}
+	if options.Quiet && options.Verbose {
+		errs = append(errs, fmt.Errorf("--quiet と --verbose は同時に指定できません"))
+	}
+	if _, err := newClipboard(options.Clipboard); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := parseSortKeys(options.Sort); err != nil {
+		errs = append(errs, err)
+	}
+
+	durations := map[string]time.Duration{
+		"BaseDelay":    config.BaseDelay,
+		"MaxDelay":     config.MaxDelay,
+		"Cooldown":     config.Cooldown,
+		"RequestDelay": config.RequestDelay,
+		"HTTPTimeout":  config.HTTPTimeout,
+		"CacheMaxAge":  config.CacheMaxAge,
+	}
+	for name, d := range durations {
+		if d < 0 {
+			errs = append(errs, fmt.Errorf("config.%s に負の値は指定できません: %v", name, d))
+		}
+	}
+
+	required := map[string][]string{
+		"PersonalityTypes": rules.PersonalityTypes,
+		"FameTypes":        rules.FameTypes,
+		"StrategyTypes":    rules.StrategyTypes,
+		"InterestWidths":   rules.InterestWidths,
+		"BasicInfoHeaders": rules.BasicInfoHeaders,
+		"AbilityHeaders":   rules.AbilityHeaders,
+		"TacticsHeaders":   rules.TacticsHeaders,
+		"SkillsHeaders":    rules.SkillsHeaders,
+	}
+	for name, values := range required {
+		if len(values) == 0 {
+			errs = append(errs, fmt.Errorf("rules.%s が空です", name))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// runRulesCommand rules サブコマンドを実行する
+func runRulesCommand(args []string) error {
+	fs := flag.NewFlagSet("rules", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "使用方法: go run main.go [--rules ファイル] rules dump\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 || fs.Arg(0) != "dump" {
+		fs.Usage()
+		return usageErrorf("rules dump を指定してください")
+	}
+
+	output, err := json.MarshalIndent(RulesFile{Config: config, Rules: rules}, "", "    ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(output))
+	return nil
+}
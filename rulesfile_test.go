@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadRulesFile(t *testing.T) {
+	savedConfig, savedRules := config, rules
+	t.Cleanup(func() { config, rules = savedConfig, savedRules })
+
+	path := filepath.Join(t.TempDir(), "rules.json")
+	content := `{"config": {"RequestDelay": "1s"}, "rules": {"InterestWidths": ["48px"]}}`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := loadRulesFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if config.RequestDelay != time.Second {
+		t.Errorf("RequestDelay = %v; want 1s", config.RequestDelay)
+	}
+	if config.HTTPTimeout != savedConfig.HTTPTimeout {
+		t.Errorf("記載のない HTTPTimeout が既定値から変わりました: %v", config.HTTPTimeout)
+	}
+	if !slices.Equal(rules.InterestWidths, []string{"48px"}) {
+		t.Errorf("InterestWidths = %v; want [48px]", rules.InterestWidths)
+	}
+	if !slices.Equal(rules.PersonalityTypes, savedRules.PersonalityTypes) {
+		t.Errorf("記載のない PersonalityTypes が既定値から変わりました: %v", rules.PersonalityTypes)
+	}
+}
+
+func TestLoadRulesFileRejectsUnknownKeys(t *testing.T) {
+	savedConfig, savedRules := config, rules
+	t.Cleanup(func() { config, rules = savedConfig, savedRules })
+
+	for _, content := range []string{
+		`{"config": {"Wokers": 8}}`,
+		`{"config": {"Resume": true}}`, // 実行ごとのオプションはルールファイルで指定できない
+		`{"rules": {"PersonalityType": ["豪胆"]}}`,
+		`{"setting": {}}`,
+	} {
+		path := filepath.Join(t.TempDir(), "rules.json")
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		err := loadRulesFile(path)
+		if err == nil || !strings.Contains(err.Error(), "unknown field") {
+			t.Errorf("%s: 未知のキーがエラーになりませんでした: %v", content, err)
+		}
+	}
+}
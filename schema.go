@@ -0,0 +1,137 @@
+package main
+
+import "strings"
+
+// ========================================
+// 出力スキーマ
+// ========================================
+
+// schemaVersion 構造化された一覧項目を持つ現在の出力スキーマのバージョン
+// バージョン1は戦法・特技・興味を ", " で連結した文字列として出力していた（--legacy-schema）
+const schemaVersion = 2
+
+// CharacterDocument JSON出力のトップレベル構造
+type CharacterDocument struct {
+	SchemaVersion int         `json:"スキーマバージョン"`
+	Characters    []Character `json:"武将"`
+}
+
+// LegacyCharacter バージョン1スキーマの武将情報
+type LegacyCharacter struct {
+	Name         string   `json:"名前"`
+	Reading      string   `json:"読み"`
+	Azana        string   `json:"字"`
+	Leadership   int      `json:"統率"`
+	Force        int      `json:"武力"`
+	Intelligence int      `json:"知力"`
+	Politics     int      `json:"政治"`
+	Charm        int      `json:"魅力"`
+	Talent       string   `json:"奇才"`
+	Interest     string   `json:"興味"`
+	Greed        string   `json:"物欲"`
+	Loyalty      int      `json:"義理"`
+	Personality  string   `json:"性格"`
+	Strategy     string   `json:"戦略傾向"`
+	DeathYear    int      `json:"没年"`
+	DeathMinus13 int      `json:"没年-13"`
+	Tactics      string   `json:"戦法"`
+	Skills       string   `json:"特技"`
+	Fame         string   `json:"重視名声"`
+	Categories   []string `json:"カテゴリ,omitempty"`
+	EntryName    string   `json:"登録名,omitempty"`
+	Page         string   `json:"ページ,omitempty"`
+	Aliases      []string `json:"別名,omitempty"`
+	Tags         []string `json:"タグ,omitempty"`
+}
+
+func toLegacyCharacter(character Character) LegacyCharacter {
+	return LegacyCharacter{
+		Name:         character.Name,
+		Reading:      character.Reading,
+		Azana:        character.Azana,
+		Leadership:   character.Leadership,
+		Force:        character.Force,
+		Intelligence: character.Intelligence,
+		Politics:     character.Politics,
+		Charm:        character.Charm,
+		Talent:       character.Talent,
+		Interest:     strings.Join(character.Interest, ", "),
+		Greed:        character.Greed,
+		Loyalty:      character.Loyalty,
+		Personality:  character.Personality,
+		Strategy:     character.Strategy,
+		DeathYear:    character.DeathYear,
+		DeathMinus13: character.DeathMinus13,
+		Tactics:      joinItemNames(character.Tactics),
+		Skills:       joinItemNames(character.Skills),
+		Fame:         character.Fame,
+		Categories:   character.Categories,
+		EntryName:    character.EntryName,
+		Page:         character.Page,
+		Aliases:      character.Aliases,
+		Tags:         character.Tags,
+	}
+}
+
+func toLegacyCharacters(characters []Character) []LegacyCharacter {
+	legacy := make([]LegacyCharacter, len(characters))
+	for i, character := range characters {
+		legacy[i] = toLegacyCharacter(character)
+	}
+	return legacy
+}
+
+func joinItemNames(items []CategorizedItem) string {
+	names := make([]string, len(items))
+	for i, item := range items {
+		names[i] = item.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+func uncategorizedItems(names []string) []CategorizedItem {
+	items := make([]CategorizedItem, len(names))
+	for i, name := range names {
+		items[i] = CategorizedItem{Name: name}
+	}
+	return items
+}
+
+// fromLegacyCharacter バージョン1スキーマの武将情報を現在の構造に変換する
+// 戦法・特技のカテゴリと補足はバージョン1に含まれないため空になる
+func fromLegacyCharacter(legacy LegacyCharacter) Character {
+	return Character{
+		Name:         legacy.Name,
+		Reading:      legacy.Reading,
+		Azana:        legacy.Azana,
+		Leadership:   legacy.Leadership,
+		Force:        legacy.Force,
+		Intelligence: legacy.Intelligence,
+		Politics:     legacy.Politics,
+		Charm:        legacy.Charm,
+		Talent:       legacy.Talent,
+		Interest:     splitJoinedList(legacy.Interest),
+		Greed:        legacy.Greed,
+		Loyalty:      legacy.Loyalty,
+		Personality:  legacy.Personality,
+		Strategy:     legacy.Strategy,
+		DeathYear:    legacy.DeathYear,
+		DeathMinus13: legacy.DeathMinus13,
+		Tactics:      uncategorizedItems(splitJoinedList(legacy.Tactics)),
+		Skills:       uncategorizedItems(splitJoinedList(legacy.Skills)),
+		Fame:         legacy.Fame,
+		Categories:   legacy.Categories,
+		EntryName:    legacy.EntryName,
+		Page:         legacy.Page,
+		Aliases:      legacy.Aliases,
+		Tags:         legacy.Tags,
+	}
+}
+
+// splitJoinedList ", " で連結された一覧を分割する
+func splitJoinedList(joined string) []string {
+	if joined == "" {
+		return []string{}
+	}
+	return strings.Split(joined, ", ")
+}
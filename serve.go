@@ -0,0 +1,285 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ========================================
+// HTTP API サーバー
+// ========================================
+
+// runServeCommand serve サブコマンドを実行する
+func runServeCommand(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", "localhost:8080", "待ち受けるアドレス")
+	dbPath := fs.String("db", "", "結果ファイルの代わりに読み込むデータベースファイル")
+	runID := fs.String("run-id", "", "--db から読み込むスナップショットの run ID（省略時は最新）")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "使用方法: go run main.go serve [オプション] <結果ファイル>\n        go run main.go serve [オプション] --db <データベースファイル>\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	var (
+		characters []Character
+		err        error
+	)
+	switch {
+	case *dbPath != "":
+		characters, err = loadFromDatabase(*dbPath, *runID)
+	case fs.NArg() == 1:
+		characters, err = loadCharactersFile(fs.Arg(0))
+	default:
+		fs.Usage()
+		return usageErrorf("結果ファイルまたは --db を指定してください")
+	}
+	if err != nil {
+		return err
+	}
+
+	slog.Info("武将データを提供します", "url", "http://"+*addr, "count", len(characters))
+	return http.ListenAndServe(*addr, newCharacterServer(characters).routes())
+}
+
+// characterServer 読み込んだ武将データを REST API として提供する
+// データは起動時に読み込んだものから変更しない
+type characterServer struct {
+	characters []Character
+}
+
+func newCharacterServer(characters []Character) *characterServer {
+	return &characterServer{characters: characters}
+}
+
+func (s *characterServer) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/characters", s.handleList)
+	mux.HandleFunc("/characters/", s.handleGet)
+	mux.HandleFunc("/tactics", s.handleItems(func(c Character) []CategorizedItem { return c.Tactics }))
+	mux.HandleFunc("/skills", s.handleItems(func(c Character) []CategorizedItem { return c.Skills }))
+	mux.HandleFunc("/talents", s.handleTalents)
+	return mux
+}
+
+// handleList GET /characters
+// クエリで絞り込む: category=奇才, 性格=猪突, min_武力=90, max_没年=220, 戦法=騎兵 など
+func (s *characterServer) handleList(w http.ResponseWriter, r *http.Request) {
+	if !allowRead(w, r) {
+		return
+	}
+
+	filtered, err := filterCharacters(s.characters, r.URL.Query())
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	writeJSON(w, r, CharacterDocument{SchemaVersion: schemaVersion, Characters: filtered})
+}
+
+// handleGet GET /characters/{名前}
+// 同名の武将がいる場合は reading=よみ で区別する
+func (s *characterServer) handleGet(w http.ResponseWriter, r *http.Request) {
+	if !allowRead(w, r) {
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, "/characters/")
+	reading := r.URL.Query().Get("reading")
+	for _, character := range s.characters {
+		if character.Name == name && (reading == "" || character.Reading == reading) {
+			writeJSON(w, r, character)
+			return
+		}
+	}
+
+	writeJSONError(w, http.StatusNotFound, fmt.Errorf("武将 %s が見つかりません", name))
+}
+
+// handleItems GET /tactics, /skills
+// 名前とカテゴリの組の重複を除いた一覧を返す
+func (s *characterServer) handleItems(items func(Character) []CategorizedItem) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !allowRead(w, r) {
+			return
+		}
+
+		seen := make(map[CategorizedItem]bool)
+		distinct := []CategorizedItem{}
+		for _, character := range s.characters {
+			for _, item := range items(character) {
+				key := CategorizedItem{Name: item.Name, Category: item.Category}
+				if !seen[key] {
+					seen[key] = true
+					distinct = append(distinct, key)
+				}
+			}
+		}
+		sort.Slice(distinct, func(i, j int) bool {
+			if distinct[i].Category != distinct[j].Category {
+				return distinct[i].Category < distinct[j].Category
+			}
+			return distinct[i].Name < distinct[j].Name
+		})
+
+		writeJSON(w, r, distinct)
+	}
+}
+
+// handleTalents GET /talents
+func (s *characterServer) handleTalents(w http.ResponseWriter, r *http.Request) {
+	if !allowRead(w, r) {
+		return
+	}
+
+	seen := make(map[string]bool)
+	talents := []string{}
+	for _, character := range s.characters {
+		if character.Talent != "" && !seen[character.Talent] {
+			seen[character.Talent] = true
+			talents = append(talents, character.Talent)
+		}
+	}
+	sort.Strings(talents)
+
+	writeJSON(w, r, talents)
+}
+
+// filterCharacters クエリパラメータの条件をすべて満たす武将を返す
+// 項目名は JSON タグ名と Go のフィールド名のどちらでも指定できる
+func filterCharacters(characters []Character, query url.Values) ([]Character, error) {
+	type condition func(Character) bool
+	var conditions []condition
+
+	for key, values := range query {
+		if key == "category" {
+			key = "カテゴリ"
+		}
+		if key == "カテゴリ" && !slices.ContainsFunc(characters, func(c Character) bool { return len(c.Categories) > 0 }) {
+			// get・spider の結果や古いファイルではすべて空になってしまうため、黙って空の一覧を返さない
+			return nil, fmt.Errorf("読み込んだ武将に所属カテゴリが記録されていないため category では絞り込めません")
+		}
+
+		bound, fieldName := "", key
+		if name, ok := strings.CutPrefix(key, "min_"); ok {
+			bound, fieldName = "min", name
+		} else if name, ok := strings.CutPrefix(key, "max_"); ok {
+			bound, fieldName = "max", name
+		}
+
+		field, ok := lookupCharacterField(fieldName)
+		if !ok {
+			return nil, fmt.Errorf("不明な項目です: %s", key)
+		}
+
+		for _, value := range values {
+			value := value // go 1.21 ではループ変数がクロージャ間で共有される
+			switch {
+			case bound != "":
+				if field.Kind != reflect.Int {
+					return nil, fmt.Errorf("%s は数値の項目ではありません", fieldName)
+				}
+				limit, err := strconv.Atoi(value)
+				if err != nil {
+					return nil, fmt.Errorf("%s には数値を指定してください: %q", key, value)
+				}
+				conditions = append(conditions, func(c Character) bool {
+					v := int(field.value(c).Int())
+					if bound == "min" {
+						return v >= limit
+					}
+					return v <= limit
+				})
+			case field.isList():
+				conditions = append(conditions, func(c Character) bool {
+					return listContains(field.value(c), value)
+				})
+			case field.Kind == reflect.Int:
+				want, err := strconv.Atoi(value)
+				if err != nil {
+					return nil, fmt.Errorf("%s には数値を指定してください: %q", key, value)
+				}
+				conditions = append(conditions, func(c Character) bool {
+					return int(field.value(c).Int()) == want
+				})
+			default:
+				conditions = append(conditions, func(c Character) bool {
+					return field.value(c).String() == value
+				})
+			}
+		}
+	}
+
+	filtered := []Character{}
+	for _, character := range characters {
+		matched := true
+		for _, cond := range conditions {
+			if !cond(character) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			filtered = append(filtered, character)
+		}
+	}
+	return filtered, nil
+}
+
+func allowRead(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return true
+	}
+	w.Header().Set("Allow", "GET, HEAD")
+	writeJSONError(w, http.StatusMethodNotAllowed, fmt.Errorf("%s には対応していません", r.Method))
+	return false
+}
+
+// writeJSON レスポンス本文のハッシュを ETag として付与し、If-None-Match が一致すれば 304 を返す
+func writeJSON(w http.ResponseWriter, r *http.Request, value any) {
+	body, err := json.MarshalIndent(value, "", "    ")
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	w.Header().Set("ETag", etag)
+
+	if matchesETag(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Write(append(body, '\n'))
+}
+
+func matchesETag(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+func writeJSONError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
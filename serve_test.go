@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newTestCharacterServer() *httptest.Server {
+	characters := []Character{
+		{Name: "曹操", Reading: "そうそう", Force: 72, Personality: "冷静", Categories: []string{"奇才"},
+			Tactics: []CategorizedItem{{Name: "突破", Category: "騎兵"}}},
+		{Name: "張飛", Reading: "ちょうひ", Force: 98, Personality: "猪突", Categories: []string{"奇才"},
+			Tactics: []CategorizedItem{{Name: "突撃", Category: "歩兵"}}},
+		{Name: "蔡琰", Reading: "さいえん", Force: 9, Personality: "温和", Categories: []string{"女性"}},
+	}
+	return httptest.NewServer(newCharacterServer(characters).routes())
+}
+
+func TestCharacterServerList(t *testing.T) {
+	server := newTestCharacterServer()
+	defer server.Close()
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"曹操", "張飛", "蔡琰"}},
+		{"?category=奇才&min_武力=90", []string{"張飛"}},
+		{"?personality=温和", []string{"蔡琰"}},
+		{"?戦法=騎兵", []string{"曹操"}},
+		// 同じ項目を繰り返した場合はすべての値を満たす武将に絞り込む
+		{"?戦法=騎兵&戦法=歩兵", nil},
+		{"?category=奇才&category=女性", nil},
+	}
+
+	for _, tt := range tests {
+		resp, err := http.Get(server.URL + "/characters" + tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var document CharacterDocument
+		err = json.NewDecoder(resp.Body).Decode(&document)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got []string
+		for _, character := range document.Characters {
+			got = append(got, character.Name)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: got %v; want %v", tt.query, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: got %v; want %v", tt.query, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestCharacterServerErrorsAndETag(t *testing.T) {
+	server := newTestCharacterServer()
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/characters?min_性格=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("数値でない項目の min_ に %d が返りました; want 400", resp.StatusCode)
+	}
+
+	resp, err = http.Get(server.URL + "/characters/呂布")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("存在しない武将に %d が返りました; want 404", resp.StatusCode)
+	}
+
+	resp, err = http.Get(server.URL + "/characters/曹操")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	etag := resp.Header.Get("ETag")
+	if resp.StatusCode != http.StatusOK || etag == "" {
+		t.Fatalf("status = %d, ETag = %q", resp.StatusCode, etag)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/characters/曹操", nil)
+	req.Header.Set("If-None-Match", etag)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotModified {
+		t.Errorf("If-None-Match が一致したのに %d が返りました; want 304", resp.StatusCode)
+	}
+}
+
+func TestFilterCharactersByCategoryWithoutCategories(t *testing.T) {
+	// get や spider の結果には所属カテゴリが記録されていない
+	characters := []Character{{Name: "曹操"}, {Name: "劉備"}}
+	if _, err := filterCharacters(characters, url.Values{"category": {"奇才"}}); err == nil {
+		t.Error("所属カテゴリのない武将を category で絞り込んでもエラーになりませんでした")
+	}
+}
+
+func TestProcessCategoryRecordsCategory(t *testing.T) {
+	useTestFetchConfig(t)
+	newTestWiki(t, map[string]string{"曹操": officerPageHTML("曹操")})
+	jsonFile := filepath.Join(t.TempDir(), "characters.json")
+	if err := os.WriteFile(jsonFile, []byte(`{"奇才": ["曹操"]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	characters, err := processCategory("奇才", jsonFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	filtered, err := filterCharacters(characters, url.Values{"category": {"奇才"}})
+	if err != nil || len(filtered) != 1 {
+		t.Errorf("category=奇才: got %+v, %v; want [曹操]", filtered, err)
+	}
+}
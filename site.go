@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// ========================================
+// サイトプロファイル
+// ========================================
+
+// CharacterExtractor 解析済みの武将ページから武将情報を抽出する
+type CharacterExtractor interface {
+	Extract(doc *html.Node) Character
+}
+
+// SiteProfile 対象wiki（ゲームタイトル）ごとのURL規則・解析ルール・抽出処理の組
+// PageName は GenerateURL の逆変換で、リンク先URLがwiki内のページでなければ false を返す
+// IndexPages は武将へのリンクを一覧にしたページ名（discover が巡回する）
+type SiteProfile struct {
+	Name        string
+	Description string
+	BaseURL     string
+	Rules       ParsingRules
+	GenerateURL func(baseURL, name string) string
+	PageName    func(baseURL, link string) (string, bool)
+	IndexPages  []string
+	Extractor   CharacterExtractor
+}
+
+const defaultSiteName = "sangokushi8r"
+
+var siteProfiles = map[string]SiteProfile{
+	defaultSiteName: {
+		Name:        defaultSiteName,
+		Description: "三國志8 REMAKE 攻略 Wiki*",
+		BaseURL:     "https://wikiwiki.jp/sangokushi8r/",
+		Rules:       rules,
+		GenerateURL: queryEscapedPageURL,
+		PageName:    queryEscapedPageName,
+		IndexPages:  []string{"武将一覧"},
+		Extractor:   remake8Extractor{},
+	},
+}
+
+// currentSite 選択中のサイトプロファイル
+var currentSite = siteProfiles[defaultSiteName]
+
+// applySiteProfile サイトプロファイルを選択し、その BaseURL と解析ルールを既定値にする
+func applySiteProfile(name string) error {
+	profile, ok := siteProfiles[name]
+	if !ok {
+		return fmt.Errorf("不明なサイトです: %s (%s のいずれかを指定してください)", name, strings.Join(siteNames(), ", "))
+	}
+
+	currentSite = profile
+	config.BaseURL = profile.BaseURL
+	rules = profile.Rules
+	return nil
+}
+
+func siteNames() []string {
+	names := make([]string, 0, len(siteProfiles))
+	for name := range siteProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// queryEscapedPageURL 武将名をクエリエスケープしてページ名とするwiki向けのURL生成
+func queryEscapedPageURL(baseURL, name string) string {
+	return baseURL + url.QueryEscape(name)
+}
+
+// queryEscapedPageName queryEscapedPageURL で作られたURLからページ名を取り出す
+// 編集画面などのクエリ付きURLやページ内リンクはページとみなさない
+func queryEscapedPageName(baseURL, link string) (string, bool) {
+	rest, ok := strings.CutPrefix(link, baseURL)
+	if !ok || rest == "" || strings.ContainsAny(rest, "?#") {
+		return "", false
+	}
+	name, err := url.QueryUnescape(rest)
+	if err != nil {
+		return "", false
+	}
+	return name, true
+}
+
+// remake8Extractor 三國志8 REMAKE wiki のページレイアウト用の抽出処理
+type remake8Extractor struct{}
+
+func (remake8Extractor) Extract(doc *html.Node) Character {
+	return parseCharacter(doc)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ========================================
+// 並び順
+// ========================================
+
+// inputSortOrder 並び替えずにJSONファイル（characters.json）の順序を保つ指定
+const inputSortOrder = "input"
+
+// sortKey 並び替えに使う項目と向き
+type sortKey struct {
+	field      characterField
+	descending bool
+}
+
+// parseSortKeys "-統率,没年,読み" のような指定を解析する（先頭の - は降順）
+// 空文字列と "input" は並び替えなしとして nil を返す
+func parseSortKeys(spec string) ([]sortKey, error) {
+	if spec = strings.TrimSpace(spec); spec == "" || spec == inputSortOrder {
+		return nil, nil
+	}
+
+	var keys []sortKey
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		name, descending := strings.CutPrefix(part, "-")
+		if name == "" {
+			return nil, fmt.Errorf("並び順の指定が不正です: %q", spec)
+		}
+
+		field, ok := lookupCharacterField(name)
+		if !ok {
+			return nil, fmt.Errorf("並び順に不明な項目が指定されました: %s", name)
+		}
+		if field.isList() {
+			return nil, fmt.Errorf("一覧項目 %s では並び替えできません", field.Name)
+		}
+
+		keys = append(keys, sortKey{field: field, descending: descending})
+	}
+	return keys, nil
+}
+
+// sortCharactersBy 指定した項目の順に安定ソートする
+// 全ての項目が等しい武将は元の順序を保つ
+func sortCharactersBy(characters []Character, keys []sortKey) {
+	if len(keys) == 0 {
+		return
+	}
+
+	sort.SliceStable(characters, func(i, j int) bool {
+		for _, key := range keys {
+			c := compareField(key.field, characters[i], characters[j])
+			if c == 0 {
+				continue
+			}
+			if key.descending {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+}
+
+func compareField(field characterField, a, b Character) int {
+	va, vb := field.value(a), field.value(b)
+	switch {
+	case field.Kind == reflect.Int:
+		return cmp.Compare(va.Int(), vb.Int())
+	case field.GoName == "Reading":
+		return compareKana(va.String(), vb.String())
+	default:
+		return strings.Compare(va.String(), vb.String())
+	}
+}
+
+// compareKana 読みを五十音順で比較する
+// カタカナはひらがなに揃えてから比較し、表記が異なるだけの読みは元の文字列で順序を決める
+func compareKana(a, b string) int {
+	if c := strings.Compare(foldKana(a), foldKana(b)); c != 0 {
+		return c
+	}
+	return strings.Compare(a, b)
+}
+
+// foldKana カタカナ（ァ〜ヶ）を対応するひらがなに変換する
+func foldKana(s string) string {
+	return strings.Map(func(r rune) rune {
+		if 'ァ' <= r && r <= 'ヶ' {
+			return r - ('ァ' - 'ぁ')
+		}
+		return r
+	}, s)
+}
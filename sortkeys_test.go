@@ -0,0 +1,49 @@
+package main
+
+import "testing"
+
+func TestSortCharactersBy(t *testing.T) {
+	characters := []Character{
+		{Name: "曹操", Reading: "そうそう", Leadership: 99, DeathYear: 220},
+		{Name: "夏侯惇", Reading: "カコウトン", Leadership: 90, DeathYear: 220},
+		{Name: "関羽", Reading: "かんう", Leadership: 99, DeathYear: 219},
+		{Name: "張飛", Reading: "ちょうひ", Leadership: 88, DeathYear: 221},
+		{Name: "劉備", Reading: "りゅうび", Leadership: 88, DeathYear: 223},
+	}
+
+	tests := []struct {
+		spec string
+		want []string
+	}{
+		{"没年", []string{"関羽", "曹操", "夏侯惇", "張飛", "劉備"}},
+		{"-統率,没年,読み", []string{"関羽", "曹操", "夏侯惇", "張飛", "劉備"}},
+		{"読み", []string{"夏侯惇", "関羽", "曹操", "張飛", "劉備"}},
+		{"没年,-読み", []string{"関羽", "曹操", "夏侯惇", "張飛", "劉備"}},
+		{"統率", []string{"張飛", "劉備", "夏侯惇", "曹操", "関羽"}},
+		{"input", []string{"曹操", "夏侯惇", "関羽", "張飛", "劉備"}},
+	}
+
+	for _, tt := range tests {
+		keys, err := parseSortKeys(tt.spec)
+		if err != nil {
+			t.Errorf("%s: %v", tt.spec, err)
+			continue
+		}
+
+		sorted := append([]Character(nil), characters...)
+		sortCharactersBy(sorted, keys)
+		for i, character := range sorted {
+			if character.Name != tt.want[i] {
+				t.Errorf("%s: %d番目 = %s; want %s", tt.spec, i, character.Name, tt.want[i])
+			}
+		}
+	}
+}
+
+func TestParseSortKeysErrors(t *testing.T) {
+	for _, spec := range []string{"身長", "-", "戦法", "統率,,没年"} {
+		if _, err := parseSortKeys(spec); err == nil {
+			t.Errorf("%q: エラーになりませんでした", spec)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"golang.org/x/net/html"
+)
+
+// ========================================
+// リンクをたどる巡回取得（spider）
+// ========================================
+
+// spiderPage 巡回で取得した1ページの結果
+// character は武将ページでなければ nil で、その場合はリンクもたどらない
+type spiderPage struct {
+	character *Character
+	links     []string
+}
+
+// contentAreaID wikiの本文を囲む要素の id（メニューバーやナビゲーションはこの外にある）
+const contentAreaID = "body"
+
+// isCharacterPage 能力値の表（rules.AbilityHeaders をすべて含む表）があるページを武将ページとみなす
+func isCharacterPage(doc *html.Node) bool {
+	for _, table := range findAllNodes(doc, "table") {
+		if containsAllTexts(table, rules.AbilityHeaders) {
+			return true
+		}
+	}
+	return false
+}
+
+// crawlPage ページを取得し、武将ページであれば武将情報とリンク先のページ名を返す
+func crawlPage(ctx context.Context, url string) (spiderPage, error) {
+	doc, err := fetchAndParseHTML(ctx, url)
+	if err != nil {
+		return spiderPage{}, err
+	}
+	if !isCharacterPage(doc) {
+		return spiderPage{}, nil
+	}
+
+	character := currentSite.Extractor.Extract(doc)
+	return spiderPage{character: &character, links: contentLinks(doc, url)}, nil
+}
+
+// contentLinks 本文中のリンク先のページ名を返す
+// メニューバーなどのリンクをたどらないよう、本文の要素がなければ表の中のリンクだけを返す
+func contentLinks(doc *html.Node, pageURL string) []string {
+	if content := findNodeByID(doc, contentAreaID); content != nil {
+		return extractPageLinks(content, pageURL)
+	}
+
+	var names []string
+	for _, table := range findAllNodes(doc, "table") {
+		for _, name := range extractPageLinks(table, pageURL) {
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// spiderTargets seeds から武将ページ内のwikiリンクを depth 段までたどり、見つかった武将を取得する
+// 取得するページ数は武将ページでないものを含めて maxPages で打ち切る（0 なら無制限）
+// 一部のページの取得に失敗した場合は取得できた武将と PartialFailureError を返す
+func spiderTargets(seeds []Target, depth, maxPages int) ([]Character, error) {
+	visited := make(map[string]bool)
+	var level []Target
+	for _, seed := range seeds {
+		if !visited[seed.URL] {
+			visited[seed.URL] = true
+			level = append(level, seed)
+		}
+	}
+
+	var (
+		found   []Target
+		results []*Character
+	)
+	fetched, failed := 0, 0
+	// 段ごとに作り直すと --max-cooldowns の上限や進捗が段ごとにリセットされるため、実行全体で共有する
+	progress := newProgress(0, 0)
+	cooldown := newCooldownPolicy()
+	for d := 0; len(level) > 0; d++ {
+		if maxPages > 0 && fetched+len(level) > maxPages {
+			slog.Warn("取得ページ数の上限に達したため巡回を打ち切ります", "limit", maxPages, "skipped", fetched+len(level)-maxPages)
+			level = level[:maxPages-fetched]
+			if len(level) == 0 {
+				break
+			}
+		}
+		slog.Info("巡回します", "depth", d, "pages", len(level))
+
+		progress.Add(len(level))
+		pages, err := runPool(level, progress, cooldown, crawlPage, nil)
+		if err != nil {
+			progress.Finish()
+			return nil, fmt.Errorf("レート制限に達したため巡回を中断しました。しばらく時間を置いてから再実行してください: %w", err)
+		}
+		fetched += len(level)
+
+		var next []Target
+		for i, page := range pages {
+			switch {
+			case page == nil:
+				failed++
+				continue
+			case page.character == nil:
+				if d == 0 {
+					slog.Warn("武将のページではありません", "name", level[i].Name, "url", level[i].URL)
+				} else {
+					slog.Debug("武将のページではないためスキップします", "name", level[i].Name)
+				}
+				continue
+			}
+
+			// たどって見つけた武将は characters.json に登録されていないため登録名を持たない
+			page.character.applyEntry(level[i].Entry)
+			found = append(found, level[i])
+			results = append(results, page.character)
+
+			if d >= depth {
+				continue
+			}
+			for _, link := range page.links {
+				url := generateURL(link)
+				if !visited[url] {
+					visited[url] = true
+					next = append(next, Target{Name: link, URL: url})
+				}
+			}
+		}
+		level = next
+	}
+
+	progress.Finish()
+
+	validationErr := enforceValidation(found, results)
+	characters := collectCharacters(results)
+
+	slog.Info("巡回が完了しました", "pages", fetched, "characters", len(characters), "failed", failed)
+	if validationErr != nil {
+		return characters, validationErr
+	}
+	if failed > 0 {
+		return characters, &PartialFailureError{Failed: failed, Total: fetched}
+	}
+	return characters, nil
+}
+
+// runSpiderCommand spider サブコマンドを実行する
+func runSpiderCommand(args []string) error {
+	fs := flag.NewFlagSet("spider", flag.ExitOnError)
+	depth := fs.Int("depth", 1, "カテゴリの武将からたどるリンクの段数（0 ならカテゴリの武将のみ）")
+	maxPages := fs.Int("max-pages", 200, "取得するページ数の上限（武将ページ以外も含む。0 で無制限）")
+	registerFetchFlags(fs)
+	registerOutputFlags(fs)
+	registerResultFlags(fs)
+	registerLogFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "使用方法: go run main.go spider [オプション] <カテゴリ名> [JSONファイル]\n例: go run main.go spider --depth 2 奇才\n")
+		fs.PrintDefaults()
+	}
+	if err := parseCommandFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		fs.Usage()
+		return usageErrorf("カテゴリ名を指定してください")
+	}
+	if *depth < 0 || *maxPages < 0 {
+		return usageErrorf("--depth と --max-pages には 0 以上を指定してください")
+	}
+
+	category, jsonFile := fs.Arg(0), config.DefaultJSONFile
+	if fs.NArg() > 1 {
+		jsonFile = fs.Arg(1)
+	}
+	seeds, err := loadCharactersFromJSON(category, jsonFile)
+	if err != nil {
+		return fmt.Errorf("キャラクターファイルの読み込みエラー: %v", err)
+	}
+
+	characters, err := spiderTargets(seeds, *depth, *maxPages)
+	if !hasResults(err) {
+		return err
+	}
+	if emitErr := emitCharacters("spider:"+category, characters); emitErr != nil {
+		return emitErr
+	}
+	return err
+}
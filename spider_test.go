@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"net/url"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+// officerPageHTML 能力値の表と関連する武将へのリンクを持つ武将ページの本文
+// 本文の外のメニューバーには「呂布」へのリンクがある
+func officerPageHTML(name string, links ...string) string {
+	body := "<strong>" + name + "(てすと)</strong><table><tr><th>統率</th><th>武力</th></tr></table>"
+	for _, link := range links {
+		body += `<a href="/wiki/` + url.QueryEscape(link) + `">` + link + "</a>"
+	}
+	return `<div id="menubar"><a href="/wiki/` + url.QueryEscape("呂布") + `">呂布</a></div><div id="body">` + body + "</div>"
+}
+
+func TestSpiderTargets(t *testing.T) {
+	useTestFetchConfig(t)
+	newTestWiki(t, map[string]string{
+		"曹操": officerPageHTML("曹操", "曹丕", "騎兵", "曹操"),
+		"曹丕": officerPageHTML("曹丕", "曹叡"),
+		"曹叡": officerPageHTML("曹叡"),
+		"騎兵": `<table><tr><th>戦法</th></tr></table>` + `<a href="/wiki/` + url.QueryEscape("呂布") + `">呂布</a>`,
+		"呂布": officerPageHTML("呂布"),
+	})
+
+	seeds := []Target{newTarget(CharacterEntry{Name: "曹操", Tags: []string{"魏"}})}
+	characters, err := spiderTargets(seeds, 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, character := range characters {
+		names = append(names, character.Name)
+	}
+	// 武将ページでない「騎兵」のリンク先やメニューバーの「呂布」はたどらず、2段目の「曹叡」は depth を超える
+	if len(characters) != 2 || names[0] != "曹操" || names[1] != "曹丕" {
+		t.Fatalf("got %v; want [曹操 曹丕]", names)
+	}
+	if characters[0].EntryName != "曹操" || len(characters[0].Tags) != 1 || characters[1].EntryName != "" {
+		t.Errorf("登録項目の記録が正しくありません: %+v", characters)
+	}
+
+	// ページ数の上限で打ち切る
+	characters, err = spiderTargets(seeds, 5, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(characters) != 2 {
+		t.Errorf("max-pages 2: got %d characters; want 2", len(characters))
+	}
+}
+
+func TestContentLinksWithoutContentArea(t *testing.T) {
+	useTestFetchConfig(t)
+	config.BaseURL = "https://example.com/wiki/"
+	doc, err := html.Parse(strings.NewReader(`<html><body><a href="/wiki/` + url.QueryEscape("呂布") + `">呂布</a>
+		<table><tr><td><a href="/wiki/` + url.QueryEscape("曹丕") + `">曹丕</a></td></tr></table></body></html>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 本文の要素がなければ表の中のリンクだけをたどる
+	if got := contentLinks(doc, config.BaseURL+url.QueryEscape("曹操")); len(got) != 1 || got[0] != "曹丕" {
+		t.Errorf("got %v; want [曹丕]", got)
+	}
+}
+
+func TestSpiderTargetsSharesProgressAcrossDepths(t *testing.T) {
+	useTestFetchConfig(t)
+	saved := slog.Default()
+	defer slog.SetDefault(saved)
+	var buf bytes.Buffer
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+
+	newTestWiki(t, map[string]string{
+		"曹操": officerPageHTML("曹操", "曹丕", "曹植"),
+		"曹丕": officerPageHTML("曹丕"),
+		"曹植": officerPageHTML("曹植"),
+	})
+	if _, err := spiderTargets([]Target{newTarget(CharacterEntry{Name: "曹操"})}, 1, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// 段ごとに進捗をやり直さず、見つかったページの分だけ総数を増やす
+	if got := strings.Count(buf.String(), "取得が完了しました"); got != 1 {
+		t.Errorf("完了のログが %d 回出力されました:\n%s", got, buf.String())
+	}
+	if !strings.Contains(buf.String(), "progress=3/3") {
+		t.Errorf("progress=3/3 が出力されていません:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+)
+
+// ========================================
+// 抽出結果の検証
+// ========================================
+
+// ValidationIssue 抽出結果の検証で見つかった問題
+type ValidationIssue struct {
+	Field   string
+	Problem string
+	Value   string
+}
+
+func (i ValidationIssue) String() string {
+	if i.Value == "" {
+		return fmt.Sprintf("%s が%s", i.Field, i.Problem)
+	}
+	return fmt.Sprintf("%s が%s (%s)", i.Field, i.Problem, i.Value)
+}
+
+const (
+	minAbility = 1
+	maxAbility = 100
+)
+
+// validateCharacter 武将情報を ParsingRules の列挙値・能力値の範囲・必須項目と照合する
+func validateCharacter(character Character) []ValidationIssue {
+	var issues []ValidationIssue
+
+	required := []struct {
+		field string
+		value string
+	}{
+		{"名前", character.Name},
+		{"読み", character.Reading},
+	}
+	for _, r := range required {
+		if r.value == "" {
+			issues = append(issues, ValidationIssue{Field: r.field, Problem: "未取得"})
+		}
+	}
+
+	if character.DeathYear == 0 {
+		issues = append(issues, ValidationIssue{Field: "没年", Problem: "未取得"})
+	}
+
+	abilities := []struct {
+		field string
+		value int
+	}{
+		{"統率", character.Leadership},
+		{"武力", character.Force},
+		{"知力", character.Intelligence},
+		{"政治", character.Politics},
+		{"魅力", character.Charm},
+	}
+	for _, a := range abilities {
+		if a.value < minAbility || a.value > maxAbility {
+			issues = append(issues, ValidationIssue{Field: a.field, Problem: "範囲外", Value: fmt.Sprint(a.value)})
+		}
+	}
+
+	enums := []struct {
+		field   string
+		value   string
+		allowed []string
+	}{
+		{"性格", character.Personality, rules.PersonalityTypes},
+		{"重視名声", character.Fame, rules.FameTypes},
+		// 戦略傾向はページ上で "-" と記載されている武将がいる
+		{"戦略傾向", character.Strategy, append(slices.Clone(rules.StrategyTypes), "-")},
+	}
+	for _, e := range enums {
+		switch {
+		case e.value == "":
+			issues = append(issues, ValidationIssue{Field: e.field, Problem: "未取得"})
+		case !slices.Contains(e.allowed, e.value):
+			issues = append(issues, ValidationIssue{Field: e.field, Problem: "不明な値", Value: e.value})
+		}
+	}
+
+	return issues
+}
+
+// reportValidation 取得できた武将を検証して問題を武将ごとに出力し、問題のあった武将の人数を返す
+func reportValidation(targets []Target, results []*Character) int {
+	invalid := 0
+	for i, character := range results {
+		if character == nil {
+			continue
+		}
+
+		issues := validateCharacter(*character)
+		if len(issues) == 0 {
+			continue
+		}
+
+		invalid++
+		descriptions := make([]string, len(issues))
+		for j, issue := range issues {
+			descriptions[j] = issue.String()
+		}
+		slog.Warn("検証警告", "name", targets[i].Name, "issues", strings.Join(descriptions, ", "))
+	}
+
+	return invalid
+}
+
+// ValidationError --strict 指定時に検証で問題が見つかったことを表す
+// 取得できた結果は出力したうえで失敗として終了する
+type ValidationError struct {
+	Invalid int
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("--strict: %d人の武将に検証エラーがあります", e.Invalid)
+}
+
+// enforceValidation 検証結果を報告し、--strict 指定時は問題があれば ValidationError を返す
+func enforceValidation(targets []Target, results []*Character) error {
+	invalid := reportValidation(targets, results)
+	if invalid > 0 && options.Strict {
+		return &ValidationError{Invalid: invalid}
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestValidateCharacter(t *testing.T) {
+	valid := Character{
+		Name:         "曹操",
+		Reading:      "そうそう",
+		Leadership:   96,
+		Force:        72,
+		Intelligence: 91,
+		Politics:     94,
+		Charm:        96,
+		Personality:  "冷静",
+		Strategy:     "積極",
+		DeathYear:    220,
+		Fame:         "高名",
+	}
+
+	if issues := validateCharacter(valid); len(issues) != 0 {
+		t.Errorf("正常な武将で問題が報告されました: %v", issues)
+	}
+
+	broken := valid
+	broken.Reading = ""
+	broken.Force = 0
+	broken.Charm = 120
+	broken.Personality = "短気"
+	broken.Fame = ""
+
+	var got []string
+	for _, issue := range validateCharacter(broken) {
+		got = append(got, issue.String())
+	}
+	want := []string{
+		"読み が未取得",
+		"武力 が範囲外 (0)",
+		"魅力 が範囲外 (120)",
+		"性格 が不明な値 (短気)",
+		"重視名声 が未取得",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("validateCharacter() = %q; want %q", got, want)
+	}
+}